@@ -20,9 +20,12 @@ import (
 	_ "modernc.org/sqlite"
 
 	"github.com/jdholdren/seymour/internal/api"
+	"github.com/jdholdren/seymour/internal/inbox"
 	"github.com/jdholdren/seymour/internal/logger"
 	"github.com/jdholdren/seymour/internal/migrations"
+	"github.com/jdholdren/seymour/internal/secrets"
 	seyqlite "github.com/jdholdren/seymour/internal/sqlite"
+	"github.com/jdholdren/seymour/internal/sync"
 	"github.com/jdholdren/seymour/internal/worker"
 )
 
@@ -35,6 +38,19 @@ type config struct {
 
 	ClaudeAPIKey    string `env:"CLAUDE_API_KEY"`
 	ClaudeAPKeyFile string `env:"CLAUDE_API_KEY_FILE"`
+
+	// Addresses, CIDR ranges, or hostnames on private networks that entries can still be fetched
+	// from for the reader, same as the worker's.
+	FetchAllow []string `env:"FETCH_ALLOW"`
+
+	// Base64 encoded 32 byte key the credentials of private feeds are sealed with, e.g. from
+	// `openssl rand -base64 32`. The api and worker need the same one.
+	CredentialsKey string `env:"CREDENTIALS_KEY"`
+
+	// Where to listen for newsletters over SMTP, e.g. :2525, leaving it empty turns the inbox off.
+	// Mail to <token>@INBOX_DOMAIN lands in the newsletter feed with that token.
+	InboxAddr   string `env:"INBOX_ADDR"`
+	InboxDomain string `env:"INBOX_DOMAIN, default=inbox.local"`
 }
 
 func main() {
@@ -96,7 +112,18 @@ func main() {
 	}
 
 	// Create and start the server
-	server := api.NewServer(cfg.Port, cfg.Cors, repo, temporalCli, cfg.ClaudeAPIKey != "")
+	box, err := secrets.NewBox(cfg.CredentialsKey)
+	if err != nil {
+		log.Fatalf("error loading credentials key: %s", err)
+	}
+
+	// Keep fetches off of our own network
+	guard, err := sync.NewGuard(cfg.FetchAllow)
+	if err != nil {
+		log.Fatalf("error parsing allowed fetch addresses: %s", err)
+	}
+
+	server := api.NewServer(cfg.Port, cfg.Cors, repo, temporalCli, guard, box, cfg.ClaudeAPIKey != "", cfg.InboxDomain)
 
 	// Set up run group
 	var g run.Group
@@ -118,6 +145,20 @@ func main() {
 		}
 	})
 
+	// Add the newsletter inbox, filed mail goes through the timeline like anything synced
+	if cfg.InboxAddr != "" {
+		mailbox := inbox.NewServer(inbox.Config{Addr: cfg.InboxAddr, Domain: cfg.InboxDomain}, repo, func(ctx context.Context) error {
+			return worker.TriggerRefreshTimelineWorkflow(ctx, temporalCli)
+		})
+		g.Add(func() error {
+			log.Printf("Inbox starting on %s", cfg.InboxAddr)
+			return mailbox.ListenAndServe()
+		}, func(error) {
+			log.Println("Shutting down inbox...")
+			_ = mailbox.Close()
+		})
+	}
+
 	// Add signal handler
 	g.Add(run.SignalHandler(ctx, os.Interrupt))
 
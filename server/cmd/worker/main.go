@@ -19,7 +19,9 @@ import (
 	_ "modernc.org/sqlite"
 
 	"github.com/jdholdren/seymour/internal/logger"
+	"github.com/jdholdren/seymour/internal/secrets"
 	seyqlite "github.com/jdholdren/seymour/internal/sqlite"
+	"github.com/jdholdren/seymour/internal/sync"
 	seyworker "github.com/jdholdren/seymour/internal/worker"
 )
 
@@ -29,6 +31,33 @@ type config struct {
 
 	ClaudeAPIKey    string `env:"CLAUDE_API_KEY"`
 	ClaudeAPKeyFile string `env:"CLAUDE_API_KEY_FILE"`
+
+	// Public url of the API server for WebSub hubs to push to, e.g. https://seymour.example.com
+	WebSubCallbackURL string `env:"WEBSUB_CALLBACK_URL"`
+
+	// Public url of the API server for entries to link back to, e.g. a watched page's diffs
+	PublicURL string `env:"PUBLIC_URL"`
+
+	// How many feeds are synced at once, overall and from any one host, and how long to
+	// wait between requests to the same host
+	SyncMaxConcurrency int           `env:"SYNC_MAX_CONCURRENCY, default=10"`
+	SyncMaxPerHost     int           `env:"SYNC_MAX_PER_HOST, default=2"`
+	SyncHostDelay      time.Duration `env:"SYNC_HOST_DELAY, default=2s"`
+
+	// How feeds are fetched, anything left unset uses the fetcher's defaults
+	FetchUserAgent      string        `env:"FETCH_USER_AGENT"`
+	FetchMaxBodySize    int64         `env:"FETCH_MAX_BODY_SIZE"`
+	FetchProxyURL       string        `env:"FETCH_PROXY_URL"`
+	FetchConnectTimeout time.Duration `env:"FETCH_CONNECT_TIMEOUT"`
+	FetchReadTimeout    time.Duration `env:"FETCH_READ_TIMEOUT"`
+
+	// Addresses, CIDR ranges, or hostnames on private networks that feeds can still be fetched
+	// from, e.g. self-hosted ones. Everything else that isn't on the public internet is refused.
+	FetchAllow []string `env:"FETCH_ALLOW"`
+
+	// Base64 encoded 32 byte key the credentials of private feeds are sealed with, e.g. from
+	// `openssl rand -base64 32`. The api and worker need the same one.
+	CredentialsKey string `env:"CREDENTIALS_KEY"`
 }
 
 func main() {
@@ -89,8 +118,36 @@ func main() {
 		option.WithAPIKey(cfg.ClaudeAPIKey),
 	)
 
+	box, err := secrets.NewBox(cfg.CredentialsKey)
+	if err != nil {
+		log.Fatalf("error loading credentials key: %s", err)
+	}
+
+	// Keep fetches off of our own network
+	guard, err := sync.NewGuard(cfg.FetchAllow)
+	if err != nil {
+		log.Fatalf("error parsing allowed fetch addresses: %s", err)
+	}
+
 	// Create the worker
-	w, err := seyworker.NewWorker(ctx, repo, temporalCli, &claudeClient)
+	w, err := seyworker.NewWorker(ctx, repo, temporalCli, &claudeClient, seyworker.Config{
+		WebSubCallbackURL: cfg.WebSubCallbackURL,
+		PublicURL:         cfg.PublicURL,
+		Sync: seyworker.SyncOptions{
+			MaxConcurrent: cfg.SyncMaxConcurrency,
+			MaxPerHost:    cfg.SyncMaxPerHost,
+			HostDelay:     cfg.SyncHostDelay,
+		},
+		Fetch: sync.FetcherOptions{
+			UserAgent:      cfg.FetchUserAgent,
+			MaxBodySize:    cfg.FetchMaxBodySize,
+			ProxyURL:       cfg.FetchProxyURL,
+			ConnectTimeout: cfg.FetchConnectTimeout,
+			ReadTimeout:    cfg.FetchReadTimeout,
+			Guard:          guard,
+		},
+		Secrets: box,
+	})
 	if err != nil {
 		log.Fatalf("Failed to create worker: %v", err)
 	}
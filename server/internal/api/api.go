@@ -15,7 +15,9 @@ import (
 	"go.temporal.io/sdk/client"
 
 	seyerrs "github.com/jdholdren/seymour/internal/errors"
+	"github.com/jdholdren/seymour/internal/secrets"
 	"github.com/jdholdren/seymour/internal/seymour"
+	"github.com/jdholdren/seymour/internal/sync"
 )
 
 func writeJSON(w http.ResponseWriter, status int, data any) error {
@@ -90,9 +92,15 @@ type Server struct {
 	repo         seymour.Repository
 	tempCli      client.Client
 	hasPromptKey bool
+
+	// Seals the credentials of private feeds, nil if there's no key for them
+	secrets *secrets.Box
+
+	// What comes after the @ in newsletters' addresses
+	inboxDomain string
 }
 
-func NewServer(port int, corsHeader string, repo seymour.Repository, temporalCli client.Client, hasPromptKey bool) *Server {
+func NewServer(port int, corsHeader string, repo seymour.Repository, temporalCli client.Client, guard *sync.Guard, box *secrets.Box, hasPromptKey bool, inboxDomain string) *Server {
 	var (
 		r        = errRouter{Router: mux.NewRouter()}
 		cache, _ = lru.New[string, FeedEntryResp](1024)
@@ -100,12 +108,15 @@ func NewServer(port int, corsHeader string, repo seymour.Repository, temporalCli
 
 	srvr := Server{
 		fetchClient: &http.Client{
-			Timeout: 2 * time.Second,
+			Timeout:   2 * time.Second,
+			Transport: guard.Transport(),
 		},
 		entryRespCache: cache,
 		repo:           repo,
 		tempCli:        temporalCli,
 		hasPromptKey:   hasPromptKey,
+		secrets:        box,
+		inboxDomain:    inboxDomain,
 		Server: &http.Server{
 			Addr:         fmt.Sprintf(":%d", port),
 			ReadTimeout:  5 * time.Second,
@@ -129,12 +140,23 @@ func NewServer(port int, corsHeader string, repo seymour.Repository, temporalCli
 	// Subscription management
 	r.HandleFuncE("/api/subscriptions", srvr.postSusbcriptions).Methods(http.MethodPost)
 	r.HandleFuncE("/api/subscriptions", srvr.getSusbcriptions).Methods(http.MethodGet)
+	r.HandleFuncE("/api/feeds/{feedID}/retry", srvr.retryFeed).Methods(http.MethodPost)
+	r.HandleFuncE("/api/feeds/{feedID}/backfill", srvr.backfillFeed).Methods(http.MethodPost)
+	r.HandleFuncE("/api/scrape/preview", srvr.previewScrape).Methods(http.MethodPost)
+	r.HandleFuncE("/api/newsletters", srvr.postNewsletter).Methods(http.MethodPost)
 
 	// Timeline view
 	r.HandleFuncE("/api/timeline", srvr.getTimeline).Methods(http.MethodGet)
 
+	// WebSub callbacks, for hubs to verify subscriptions and push content
+	r.HandleFuncE("/api/websub/{feedID}", srvr.verifyWebSub).Methods(http.MethodGet)
+	r.HandleFuncE("/api/websub/{feedID}", srvr.receiveWebSub).Methods(http.MethodPost)
+
 	// Reader view
 	r.HandleFuncE("/api/feed-entries/{feedEntryID}", srvr.getFeedEntry).Methods(http.MethodGet)
 
+	// What changed on a watched page
+	r.HandleFuncE("/api/snapshots/{snapshotID}/diff", srvr.getSnapshotDiff).Methods(http.MethodGet)
+
 	return &srvr
 }
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	seyerrs "github.com/jdholdren/seymour/internal/errors"
+	"github.com/jdholdren/seymour/internal/inbox"
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+type PostNewsletterReq struct {
+	// What to call it, otherwise it's named after whoever sends the first issue
+	Title string `json:"title"`
+}
+
+// NewsletterResp is a newsletter feed along with where to have it sent.
+type NewsletterResp struct {
+	Feed    FeedResp `json:"feed"`
+	Address string   `json:"address"`
+}
+
+// postNewsletter creates a newsletter feed with an address of its own and subscribes to it.
+// Anything mailed to the address shows up as its entries.
+func (s Server) postNewsletter(w http.ResponseWriter, r *http.Request) error {
+	var (
+		ctx  = r.Context()
+		body PostNewsletterReq
+	)
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return seyerrs.E(err, http.StatusBadRequest)
+	}
+
+	token, err := inbox.NewToken()
+	if err != nil {
+		return err
+	}
+	feed, err := s.repo.InsertFeed(ctx, inbox.FeedURL(token), seymour.FeedKindNewsletter)
+	if err != nil {
+		return fmt.Errorf("error inserting newsletter: %s", err)
+	}
+
+	address := inbox.Address(feed, s.inboxDomain)
+	if err := s.repo.UpdateFeed(ctx, feed.ID, seymour.UpdateFeedArgs{
+		Title:       strings.TrimSpace(body.Title),
+		Description: "Newsletters sent to " + address,
+	}); err != nil {
+		return err
+	}
+	if err := s.repo.CreateSubscription(ctx, feed.ID); err != nil {
+		return err
+	}
+
+	feed, err = s.repo.Feed(ctx, feed.ID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusCreated, NewsletterResp{
+		Feed:    apiFeed(feed),
+		Address: address,
+	})
+}
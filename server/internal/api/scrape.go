@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	seyerrs "github.com/jdholdren/seymour/internal/errors"
+	"github.com/jdholdren/seymour/internal/seymour"
+	"github.com/jdholdren/seymour/internal/sync"
+	"github.com/jdholdren/seymour/internal/worker"
+)
+
+// validateScrape checks the page and selectors of a scraped feed.
+func validateScrape(pageURL string, cfg seymour.ScrapeConfig) error {
+	u, err := url.Parse(pageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return seyerrs.E("a scraped feed's url must be an http or https page", http.StatusBadRequest)
+	}
+	if err := sync.ValidateScrapeConfig(cfg); err != nil {
+		return seyerrs.E(err, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+type PreviewScrapeReq struct {
+	URL    string               `json:"url"`
+	Scrape seymour.ScrapeConfig `json:"scrape"`
+}
+
+func (req PreviewScrapeReq) Validate() error {
+	return validateScrape(req.URL, req.Scrape)
+}
+
+type ScrapePreviewResp struct {
+	Title string        `json:"title"`
+	Items []ScrapedItem `json:"items"`
+}
+
+// ScrapedItem is an entry picked out of the page, as it would be synced.
+type ScrapedItem struct {
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	PublishDate *time.Time `json:"publish_date"` // Nil if the item didn't have one that could be read
+	Summary     string     `json:"summary"`
+}
+
+// previewScrape shows what a scraped feed would pick out of its page, before subscribing to it.
+func (s Server) previewScrape(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	body, err := decodeValid[PreviewScrapeReq](r.Body)
+	var seyErr *seyerrs.Error
+	if errors.As(err, &seyErr) {
+		return seyErr
+	}
+	if err != nil {
+		return seyerrs.E(err, http.StatusBadRequest)
+	}
+
+	preview, err := worker.TriggerPreviewScrapeWorkflow(ctx, s.tempCli, body.URL, body.Scrape)
+	if errors.As(err, &seyErr) {
+		return seyErr
+	}
+	if err != nil {
+		return err
+	}
+
+	resp := ScrapePreviewResp{
+		Title: preview.Title,
+		Items: make([]ScrapedItem, 0, len(preview.Entries)),
+	}
+	for _, entry := range preview.Entries {
+		var published *time.Time
+		if !entry.PublishTime.Time.IsZero() {
+			published = &entry.PublishTime.Time
+		}
+
+		resp.Items = append(resp.Items, ScrapedItem{
+			Title:       entry.Title,
+			URL:         entry.Link,
+			PublishDate: published,
+			Summary:     entry.Description,
+		})
+	}
+
+	return writeJSON(w, http.StatusOK, resp)
+}
@@ -6,25 +6,113 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
 	"time"
 
 	readability "github.com/go-shiori/go-readability"
 	"github.com/gorilla/mux"
 	"github.com/sym01/htmlsanitizer"
+	"golang.org/x/net/http/httpguts"
 
 	seyerrs "github.com/jdholdren/seymour/internal/errors"
+	"github.com/jdholdren/seymour/internal/inbox"
 	"github.com/jdholdren/seymour/internal/seymour"
 	"github.com/jdholdren/seymour/internal/worker"
 )
 
 type PostSubscriptionReq struct {
+	// A feed, a site that has one, or a source's url like github:owner/repo
 	FeedURL string `json:"feed_url"`
+
+	// For private feeds, stored sealed and never sent back
+	Credentials *seymour.FeedCredentials `json:"credentials,omitempty"`
+
+	// To also pull in the feed's older entries once it's subscribed to
+	Backfill *BackfillReq `json:"backfill,omitempty"`
+
+	// For pages without a feed: how to pick the entries out of the page at the url
+	Scrape *seymour.ScrapeConfig `json:"scrape,omitempty"`
+
+	// To follow the page at the url for changes instead, with an entry each time it does
+	Watch *seymour.WatchConfig `json:"watch,omitempty"`
+}
+
+// BackfillReq asks for a feed's older entries from its paged or archived history.
+type BackfillReq struct {
+	MaxPages int        `json:"max_pages"` // How many pages to walk back through, up to 100
+	Since    *time.Time `json:"since"`     // To skip anything published before then
+}
+
+func (req BackfillReq) options() worker.BackfillOptions {
+	opts := worker.BackfillOptions{MaxPages: req.MaxPages}
+	if req.Since != nil {
+		opts.Since = *req.Since
+	}
+
+	return opts
 }
 
+func validateBackfillReq(req BackfillReq) error {
+	if req.MaxPages < 0 || req.MaxPages > 100 {
+		return seyerrs.E("max_pages must be between 0 and 100", http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// Headers that are the fetcher's business, not something credentials get to set.
+var reservedHeaders = []string{"Host", "Content-Length", "Transfer-Encoding", "Connection", "Accept-Encoding", "User-Agent"}
+
 func validatePostSubscriptionReq(req PostSubscriptionReq) error {
 	if req.FeedURL == "" {
 		return seyerrs.E("feed_url is required", http.StatusBadRequest)
 	}
+	if req.Backfill != nil {
+		if err := validateBackfillReq(*req.Backfill); err != nil {
+			return err
+		}
+	}
+	if req.Scrape != nil && req.Watch != nil {
+		return seyerrs.E("a page can be scraped or watched, not both", http.StatusBadRequest)
+	}
+	if req.Scrape != nil {
+		if err := validateScrape(req.FeedURL, *req.Scrape); err != nil {
+			return err
+		}
+	}
+	if req.Watch != nil {
+		if err := validateWatch(req.FeedURL, *req.Watch); err != nil {
+			return err
+		}
+	}
+	if req.Credentials == nil {
+		return nil
+	}
+
+	creds := *req.Credentials
+	if creds.Password != "" && creds.Username == "" {
+		return seyerrs.E("credentials.username is required with a password", http.StatusBadRequest)
+	}
+	if creds.Token != "" && creds.Username != "" {
+		return seyerrs.E("credentials can have a username or a token, not both", http.StatusBadRequest)
+	}
+	for name, value := range creds.Headers {
+		if !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+			return seyerrs.E(fmt.Sprintf("credentials.headers has an invalid header: %q", name), http.StatusBadRequest)
+		}
+		if slices.ContainsFunc(reservedHeaders, func(h string) bool { return strings.EqualFold(h, name) }) {
+			return seyerrs.E(fmt.Sprintf("credentials.headers can't set %s", name), http.StatusBadRequest)
+		}
+		if strings.EqualFold(name, "Authorization") && (creds.Username != "" || creds.Token != "") {
+			return seyerrs.E("credentials.headers can't set Authorization alongside a username or token", http.StatusBadRequest)
+		}
+	}
+	for name := range creds.Query {
+		if name == "" {
+			return seyerrs.E("credentials.query can't have an empty parameter name", http.StatusBadRequest)
+		}
+	}
 
 	return nil
 }
@@ -33,8 +121,10 @@ type FeedResp struct {
 	ID           string     `json:"id"`
 	Title        string     `json:"title"`
 	URL          string     `json:"url"`
+	Kind         string     `json:"kind"` // "feed", or the source it comes from, e.g. "github"
 	Description  string     `json:"description"`
 	LastSyncedAt *time.Time `json:"last_synced_at"`
+	Status       string     `json:"status"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 }
@@ -59,13 +149,21 @@ func apiFeed(f seymour.Feed) FeedResp {
 		ID:           f.ID,
 		Title:        title,
 		URL:          f.URL,
+		Kind:         string(f.Kind),
 		Description:  desc,
 		LastSyncedAt: lastSynced,
+		Status:       string(f.Status),
 		CreatedAt:    f.CreatedAt.Time,
 		UpdatedAt:    f.UpdatedAt.Time,
 	}
 }
 
+// FeedCandidatesResp is returned when subscribing to a url that offers more than one feed.
+type FeedCandidatesResp struct {
+	Message    string   `json:"message"`
+	Candidates []string `json:"candidates"`
+}
+
 func (s Server) postSusbcriptions(w http.ResponseWriter, r *http.Request) error {
 	var (
 		ctx  = r.Context()
@@ -78,8 +176,44 @@ func (s Server) postSusbcriptions(w http.ResponseWriter, r *http.Request) error
 		return err
 	}
 
+	// Credentials are sealed before they go anywhere, the worker opens them when it fetches
+	var sealed string
+	if body.Credentials != nil && !body.Credentials.IsZero() {
+		if s.secrets == nil {
+			return seyerrs.E("credentials can't be stored without a CREDENTIALS_KEY", http.StatusBadRequest)
+		}
+
+		plaintext, err := json.Marshal(body.Credentials)
+		if err != nil {
+			return fmt.Errorf("error encoding credentials: %s", err)
+		}
+		if sealed, err = s.secrets.Seal(plaintext); err != nil {
+			return fmt.Errorf("error sealing credentials: %s", err)
+		}
+	}
+
+	// Scraped and watched pages are sources of their own, with their settings to go along with the page
+	var (
+		feedURL      = body.FeedURL
+		sourceConfig string
+	)
+	if body.Scrape != nil {
+		cfg, err := json.Marshal(body.Scrape)
+		if err != nil {
+			return fmt.Errorf("error encoding scrape config: %s", err)
+		}
+		feedURL, sourceConfig = "scrape:"+body.FeedURL, string(cfg)
+	}
+	if body.Watch != nil {
+		cfg, err := json.Marshal(body.Watch)
+		if err != nil {
+			return fmt.Errorf("error encoding watch config: %s", err)
+		}
+		feedURL, sourceConfig = "watch:"+body.FeedURL, string(cfg)
+	}
+
 	// Start the workflow to create it and verify it
-	feedID, err := worker.TriggerCreateFeedWorkflow(ctx, s.tempCli, body.FeedURL)
+	res, err := worker.TriggerCreateFeedWorkflow(ctx, s.tempCli, feedURL, sealed, sourceConfig)
 	var seyErr *seyerrs.Error
 	if errors.As(err, &seyErr) {
 		return seyErr
@@ -87,7 +221,16 @@ func (s Server) postSusbcriptions(w http.ResponseWriter, r *http.Request) error
 	if err != nil {
 		return err
 	}
-	feed, err := s.repo.Feed(ctx, feedID)
+
+	// The url pointed at a site with several feeds: let the caller pick one
+	if len(res.Candidates) > 0 {
+		return writeJSON(w, http.StatusMultipleChoices, FeedCandidatesResp{
+			Message:    "multiple feeds found, subscribe to one of the candidates",
+			Candidates: res.Candidates,
+		})
+	}
+
+	feed, err := s.repo.Feed(ctx, res.FeedID)
 	if err != nil {
 		return err
 	}
@@ -97,6 +240,12 @@ func (s Server) postSusbcriptions(w http.ResponseWriter, r *http.Request) error
 		return err
 	}
 
+	if body.Backfill != nil {
+		if err := worker.TriggerBackfillWorkflow(ctx, s.tempCli, feed.ID, body.Backfill.options()); err != nil {
+			return err
+		}
+	}
+
 	return writeJSON(w, http.StatusCreated, apiFeed(feed))
 }
 
@@ -107,6 +256,21 @@ type SubscriptionResp struct {
 	FeedName        string     `json:"feed_name"`
 	FeedDescription string     `json:"feed_description"`
 	LastSynced      *time.Time `json:"last_synced"`
+	ParseWarning    string     `json:"parse_warning,omitempty"`
+
+	// Health of the feed's syncs, "broken" feeds need a retry to be synced again
+	Status              string     `json:"status"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	NextSyncAt          *time.Time `json:"next_sync_at"`
+
+	// Whether the feed is fetched with credentials, which are never sent back
+	HasCredentials bool `json:"has_credentials"`
+
+	// For newsletters, the address to have them sent to
+	Address string `json:"address,omitempty"`
 }
 
 type SubscriptionListResp struct {
@@ -136,6 +300,11 @@ func (s Server) getSusbcriptions(w http.ResponseWriter, r *http.Request) error {
 			feedName        string
 			feedDescription string
 			lastSynced      *time.Time
+			parseWarning    string
+			lastError       string
+			lastErrorAt     *time.Time
+			lastSuccessAt   *time.Time
+			nextSyncAt      *time.Time
 		)
 		if feed.Title != nil {
 			feedName = *feed.Title
@@ -146,6 +315,21 @@ func (s Server) getSusbcriptions(w http.ResponseWriter, r *http.Request) error {
 		if feed.LastSyncedAt != nil {
 			lastSynced = &feed.LastSyncedAt.Time
 		}
+		if feed.ParseWarning != nil {
+			parseWarning = *feed.ParseWarning
+		}
+		if feed.LastError != nil {
+			lastError = *feed.LastError
+		}
+		if feed.LastErrorAt != nil {
+			lastErrorAt = &feed.LastErrorAt.Time
+		}
+		if feed.LastSuccessAt != nil {
+			lastSuccessAt = &feed.LastSuccessAt.Time
+		}
+		if feed.NextSyncAt != nil {
+			nextSyncAt = &feed.NextSyncAt.Time
+		}
 
 		resp.Subscriptions = append(resp.Subscriptions, SubscriptionResp{
 			ID:              sub.ID,
@@ -154,39 +338,143 @@ func (s Server) getSusbcriptions(w http.ResponseWriter, r *http.Request) error {
 			FeedName:        feedName,
 			FeedDescription: feedDescription,
 			LastSynced:      lastSynced,
+			ParseWarning:    parseWarning,
+
+			Status:              string(feed.Status),
+			ConsecutiveFailures: feed.ConsecutiveFailures,
+			LastError:           lastError,
+			LastErrorAt:         lastErrorAt,
+			LastSuccessAt:       lastSuccessAt,
+			NextSyncAt:          nextSyncAt,
+			HasCredentials:      feed.Credentials != nil,
+			Address:             inbox.Address(feed, s.inboxDomain),
 		})
 	}
 	return writeJSON(w, http.StatusCreated, resp)
 }
 
+// retryFeed puts a feed back into rotation, e.g. after it was marked broken.
+//
+// It's due right away, so the next round of syncs picks it up.
+func (s Server) retryFeed(w http.ResponseWriter, r *http.Request) error {
+	var (
+		ctx    = r.Context()
+		feedID = mux.Vars(r)["feedID"]
+	)
+
+	_, err := s.repo.Feed(ctx, feedID)
+	if errors.Is(err, seymour.ErrNotFound) {
+		return seyerrs.E("feed not found", http.StatusNotFound)
+	}
+	if err != nil {
+		return err
+	}
+
+	noFailures := 0
+	if err := s.repo.UpdateFeed(ctx, feedID, seymour.UpdateFeedArgs{
+		Status:              seymour.FeedStatusActive,
+		ConsecutiveFailures: &noFailures,
+		NextSync:            seymour.DBTime{Time: time.Now()},
+	}); err != nil {
+		return err
+	}
+
+	feed, err := s.repo.Feed(ctx, feedID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, apiFeed(feed))
+}
+
+// backfillFeed starts pulling in the feed's older entries, without waiting for them.
+func (s Server) backfillFeed(w http.ResponseWriter, r *http.Request) error {
+	var (
+		ctx    = r.Context()
+		feedID = mux.Vars(r)["feedID"]
+		body   BackfillReq
+	)
+	// The body's optional, the defaults are fine
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return seyerrs.E(err, http.StatusBadRequest)
+		}
+	}
+	if err := validateBackfillReq(body); err != nil {
+		return err
+	}
+
+	feed, err := s.repo.Feed(ctx, feedID)
+	if errors.Is(err, seymour.ErrNotFound) {
+		return seyerrs.E("feed not found", http.StatusNotFound)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := worker.TriggerBackfillWorkflow(ctx, s.tempCli, feed.ID, body.options()); err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusAccepted, apiFeed(feed))
+}
+
 type TimelineResp struct {
 	Items      []TimelineEntry `json:"items"`
 	Pagination paginationMeta  `json:"pagination"`
 }
 
 type TimelineEntry struct {
-	EntryID     string    `json:"entry_id"`
-	FeedName    string    `json:"feed_name"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	URL         string    `json:"url"`
-	PublishDate time.Time `json:"publish_date"`
+	EntryID     string          `json:"entry_id"`
+	FeedName    string          `json:"feed_name"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	URL         string          `json:"url"`
+	PublishDate time.Time       `json:"publish_date"`
+	Media       []TimelineMedia `json:"media,omitempty"`
+	Authors     []string        `json:"authors,omitempty"`
+	Categories  []string        `json:"categories,omitempty"`
+
+	// Set when the feed changed the entry after it was first synced
+	Updated   bool       `json:"updated"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+
+	// Set when the entry came from the feed's history rather than being synced when it was new
+	Backfilled bool `json:"backfilled"`
+}
+
+// TimelineMedia is a playable or previewable attachment on a timeline entry.
+type TimelineMedia struct {
+	URL          string `json:"url"`
+	MIMEType     string `json:"mime_type"`
+	Length       int64  `json:"length,omitempty"`
+	Duration     int64  `json:"duration,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
 }
 
 func (s Server) getTimeline(w http.ResponseWriter, r *http.Request) error {
 	var (
-		ctx    = r.Context()
-		feedID = r.URL.Query().Get("feed_id")
+		ctx      = r.Context()
+		feedID   = r.URL.Query().Get("feed_id")
+		author   = r.URL.Query().Get("author")
+		category = r.URL.Query().Get("category")
+
+		// Older entries pulled in by a backfill are only shown when asked for
+		includeBackfilled = r.URL.Query().Get("include_backfilled") == "true"
 	)
 
 	// Parse pagination parameters
 	limit, offset := parsePaginationParams(r, 20, 100) // default=20, max=100
 
 	args := seymour.TimelineEntriesArgs{
-		Status: seymour.TimelineEntryStatusApproved,
-		FeedID: feedID,
-		Limit:  uint64(limit),
-		Offset: uint64(offset),
+		Status:   seymour.TimelineEntryStatusApproved,
+		FeedID:   feedID,
+		Author:   author,
+		Category: category,
+		Limit:    uint64(limit),
+		Offset:   uint64(offset),
+
+		IncludeBackfilled: includeBackfilled,
 	}
 
 	// Get count and entries
@@ -244,6 +532,22 @@ func (s Server) getTimeline(w http.ResponseWriter, r *http.Request) error {
 			feedTitle = *feed.Title
 		}
 
+		var media []TimelineMedia
+		for _, m := range feedEntry.Media {
+			media = append(media, TimelineMedia{
+				URL:          m.URL,
+				MIMEType:     m.MIMEType,
+				Length:       m.Length,
+				Duration:     m.Duration,
+				ThumbnailURL: m.ThumbnailURL,
+			})
+		}
+
+		var updatedAt *time.Time
+		if feedEntry.UpdatedAt != nil {
+			updatedAt = &feedEntry.UpdatedAt.Time
+		}
+
 		items = append(items, TimelineEntry{
 			EntryID:     feedEntry.ID,
 			FeedName:    feedTitle,
@@ -251,6 +555,12 @@ func (s Server) getTimeline(w http.ResponseWriter, r *http.Request) error {
 			Description: feedEntry.Description,
 			URL:         feedEntry.Link,
 			PublishDate: feedEntry.PublishTime.Time,
+			Media:       media,
+			Authors:     feedEntry.Authors,
+			Categories:  feedEntry.Categories,
+			Updated:     updatedAt != nil,
+			UpdatedAt:   updatedAt,
+			Backfilled:  feedEntry.Backfilled,
 		})
 	}
 
@@ -264,13 +574,26 @@ func (s Server) getTimeline(w http.ResponseWriter, r *http.Request) error {
 }
 
 type FeedEntryResp struct {
-	ID            string    `json:"id"`
-	FeedID        string    `json:"feed_id"`
-	URL           string    `json:"url"`
-	Title         string    `json:"title"`
-	Description   string    `json:"description"`
-	CreatedAt     time.Time `json:"created_at"`
-	ReaderContent string    `json:"reader_content"`
+	ID            string              `json:"id"`
+	FeedID        string              `json:"feed_id"`
+	URL           string              `json:"url"`
+	Title         string              `json:"title"`
+	Description   string              `json:"description"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     *time.Time          `json:"updated_at,omitempty"`
+	Revisions     []EntryRevisionResp `json:"revisions,omitempty"`
+	ReaderContent string              `json:"reader_content"`
+
+	// For newsletters, the List-Unsubscribe header they were sent with
+	ListUnsubscribe string `json:"list_unsubscribe,omitempty"`
+}
+
+// EntryRevisionResp is a previous version of an entry, from before the feed changed it.
+type EntryRevisionResp struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	URL         string    `json:"url"`
+	ReplacedAt  time.Time `json:"replaced_at"`
 }
 
 func (s Server) getFeedEntry(w http.ResponseWriter, r *http.Request) error {
@@ -284,11 +607,53 @@ func (s Server) getFeedEntry(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	// Cache results for less processing and prevent refetches
-	if resp, ok := s.entryRespCache.Get(feedEntryID); ok {
+	// Cache results for less processing and prevent refetches.
+	// The key includes when it was updated so changed entries aren't served stale.
+	var (
+		cacheKey  = entry.ID
+		updatedAt *time.Time
+	)
+	if entry.UpdatedAt != nil {
+		updatedAt = &entry.UpdatedAt.Time
+		cacheKey = fmt.Sprintf("%s@%s", entry.ID, updatedAt.Format(time.RFC3339))
+	}
+	if resp, ok := s.entryRespCache.Get(cacheKey); ok {
 		return writeJSON(w, http.StatusOK, resp)
 	}
 
+	var revisions []EntryRevisionResp
+	if updatedAt != nil {
+		revs, err := s.repo.EntryRevisions(ctx, entry.ID)
+		if err != nil {
+			return err
+		}
+		for _, rev := range revs {
+			revisions = append(revisions, EntryRevisionResp{
+				Title:       rev.Title,
+				Description: rev.Description,
+				URL:         rev.Link,
+				ReplacedAt:  rev.CreatedAt.Time,
+			})
+		}
+	}
+
+	// The feed gave us the full content already, no need to go to the site
+	if entry.Content != "" {
+		return writeJSON(w, http.StatusOK, FeedEntryResp{
+			ID:            entry.ID,
+			FeedID:        entry.FeedID,
+			URL:           entry.Link,
+			Title:         entry.Title,
+			Description:   entry.Description,
+			CreatedAt:     entry.CreatedAt.Time,
+			UpdatedAt:     updatedAt,
+			Revisions:     revisions,
+			ReaderContent: entry.Content,
+
+			ListUnsubscribe: entry.ListUnsubscribe,
+		})
+	}
+
 	// TODO: Ensure this at sync time in the workflow
 	u, err := url.Parse(entry.GUID)
 	if err != nil {
@@ -315,6 +680,14 @@ func (s Server) getFeedEntry(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
+	// Entries synced without reading their page, like a sitemap's, are titled with their url until now
+	if entry.Title == entry.Link && article.Title != "" {
+		entry.Title, entry.Description = article.Title, article.Excerpt
+		if err := s.repo.SetEntrySummary(ctx, entry.ID, entry.Title, entry.Description); err != nil {
+			return err
+		}
+	}
+
 	ret := FeedEntryResp{
 		ID:            entry.ID,
 		FeedID:        entry.FeedID,
@@ -322,10 +695,12 @@ func (s Server) getFeedEntry(w http.ResponseWriter, r *http.Request) error {
 		Title:         entry.Title,
 		Description:   entry.Description,
 		CreatedAt:     entry.CreatedAt.Time,
+		UpdatedAt:     updatedAt,
+		Revisions:     revisions,
 		ReaderContent: contents,
 	}
 	// Add to the cache for next time
-	s.entryRespCache.Add(entry.ID, ret)
+	s.entryRespCache.Add(cacheKey, ret)
 
 	return writeJSON(w, http.StatusOK, ret)
 }
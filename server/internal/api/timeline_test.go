@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/jdholdren/seymour/internal/migrations"
+	"github.com/jdholdren/seymour/internal/seymour"
+	"github.com/jdholdren/seymour/internal/sqlite"
+)
+
+func TestRetryFeed(t *testing.T) {
+	ctx := context.Background()
+	dbx, err := sqlx.Open("sqlite", "file:"+t.TempDir()+"/seymour.sqlite")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = dbx.Close() })
+	require.NoError(t, migrations.Run(dbx))
+	repo := sqlite.New(dbx)
+
+	feed, err := repo.InsertFeed(ctx, "https://example.com/feed.xml", seymour.FeedKindFeed)
+	require.NoError(t, err)
+	failures := 10
+	require.NoError(t, repo.UpdateFeed(ctx, feed.ID, seymour.UpdateFeedArgs{
+		Status:              seymour.FeedStatusBroken,
+		ConsecutiveFailures: &failures,
+		NextSync:            seymour.DBTime{Time: time.Now().Add(24 * time.Hour)},
+	}))
+
+	s := Server{repo: repo}
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/api/feeds/"+feed.ID+"/retry", nil), map[string]string{"feedID": feed.ID})
+	w := httptest.NewRecorder()
+	require.NoError(t, s.retryFeed(w, req))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	feed, err = repo.Feed(ctx, feed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, seymour.FeedStatusActive, feed.Status)
+	assert.Zero(t, feed.ConsecutiveFailures)
+
+	// Back in rotation for the next sync
+	due, err := repo.DueFeedIDs(ctx, time.Now().Add(time.Second), "", 10)
+	require.NoError(t, err)
+	assert.Contains(t, due, feed.ID)
+
+	t.Run("unknown feed", func(t *testing.T) {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/api/feeds/nope/retry", nil), map[string]string{"feedID": "nope"})
+		assert.Error(t, s.retryFeed(httptest.NewRecorder(), req))
+	})
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	seyerrs "github.com/jdholdren/seymour/internal/errors"
+	"github.com/jdholdren/seymour/internal/seymour"
+	"github.com/jdholdren/seymour/internal/sync"
+)
+
+// validateWatch checks the page and ignore selectors of a watched page.
+func validateWatch(pageURL string, cfg seymour.WatchConfig) error {
+	u, err := url.Parse(pageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return seyerrs.E("a watched page's url must be an http or https page", http.StatusBadRequest)
+	}
+	if err := sync.ValidateWatchConfig(cfg); err != nil {
+		return seyerrs.E(err, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// SnapshotDiffResp is how a watched page changed from one snapshot to the next.
+type SnapshotDiffResp struct {
+	ID      string          `json:"id"`
+	FeedID  string          `json:"feed_id"`
+	URL     string          `json:"url"` // The watched page
+	Title   string          `json:"title"`
+	Summary string          `json:"summary"`
+	Since   *time.Time      `json:"since"` // When the previous snapshot was taken, nil for the first one
+	TakenAt time.Time       `json:"taken_at"`
+	Lines   []sync.DiffLine `json:"lines"` // Every line of both snapshots, marked as the same, added, or removed
+}
+
+func (s Server) getSnapshotDiff(w http.ResponseWriter, r *http.Request) error {
+	var (
+		ctx        = r.Context()
+		snapshotID = mux.Vars(r)["snapshotID"]
+	)
+
+	snapshot, err := s.repo.Snapshot(ctx, snapshotID)
+	if errors.Is(err, seymour.ErrNotFound) {
+		return seyerrs.E("snapshot not found", http.StatusNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	feed, err := s.repo.Feed(ctx, snapshot.FeedID)
+	if err != nil {
+		return err
+	}
+
+	// The first snapshot is all new
+	var since *time.Time
+	previous, err := s.repo.PreviousSnapshot(ctx, snapshot.ID)
+	switch {
+	case err == nil:
+		since = &previous.CreatedAt.Time
+	case !errors.Is(err, seymour.ErrNotFound):
+		return err
+	}
+	lines := sync.Diff(previous.Content, snapshot.Content)
+
+	return writeJSON(w, http.StatusOK, SnapshotDiffResp{
+		ID:      snapshot.ID,
+		FeedID:  feed.ID,
+		URL:     strings.TrimPrefix(feed.URL, "watch:"),
+		Title:   snapshot.Title,
+		Summary: sync.SummarizeDiff(lines),
+		Since:   since,
+		TakenAt: snapshot.CreatedAt.Time,
+		Lines:   lines,
+	})
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	seyerrs "github.com/jdholdren/seymour/internal/errors"
+	"github.com/jdholdren/seymour/internal/seymour"
+	"github.com/jdholdren/seymour/internal/sync"
+	"github.com/jdholdren/seymour/internal/worker"
+)
+
+// The largest pushed feed document that's accepted.
+const maxPushedBodySize = 5 << 20
+
+// verifyWebSub answers a hub checking that we asked for the subscription.
+//
+// See https://www.w3.org/TR/websub/#hub-verifies-intent.
+func (s Server) verifyWebSub(w http.ResponseWriter, r *http.Request) error {
+	var (
+		ctx    = r.Context()
+		feedID = mux.Vars(r)["feedID"]
+		query  = r.URL.Query()
+	)
+
+	sub, err := s.repo.WebSubSubscription(ctx, feedID)
+	if errors.Is(err, seymour.ErrNotFound) {
+		return seyerrs.E("no subscription for feed", http.StatusNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if query.Get("hub.topic") != sub.TopicURL {
+		return seyerrs.E("topic doesn't match subscription", http.StatusNotFound)
+	}
+
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		// Only a request we're waiting on, renewals included, since they go back to pending
+		if sub.State != seymour.WebSubStatePending {
+			return seyerrs.E("no pending subscription for feed", http.StatusNotFound)
+		}
+
+		lease, err := strconv.ParseInt(query.Get("hub.lease_seconds"), 10, 64)
+		if err != nil {
+			return seyerrs.E("invalid hub.lease_seconds", http.StatusBadRequest)
+		}
+
+		sub.State = seymour.WebSubStateVerified
+		sub.LeaseExpiresAt = &seymour.DBTime{Time: time.Now().Add(time.Duration(lease) * time.Second)}
+		if err := s.repo.UpsertWebSubSubscription(ctx, sub); err != nil {
+			return err
+		}
+
+		// Echoing the challenge confirms it
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, query.Get("hub.challenge"))
+		return nil
+	case "denied":
+		sub.State = seymour.WebSubStateDenied
+		if err := s.repo.UpsertWebSubSubscription(ctx, sub); err != nil {
+			return err
+		}
+
+		slog.Warn("websub subscription denied", "feed_id", feedID, "reason", query.Get("hub.reason"))
+		w.WriteHeader(http.StatusOK)
+		return nil
+	default:
+		// We never unsubscribe, so anything else wasn't asked for
+		return seyerrs.E("unexpected hub.mode", http.StatusNotFound)
+	}
+}
+
+// receiveWebSub takes content pushed by a hub and ingests it like a sync would.
+//
+// See https://www.w3.org/TR/websub/#content-distribution.
+func (s Server) receiveWebSub(w http.ResponseWriter, r *http.Request) error {
+	var (
+		ctx    = r.Context()
+		feedID = mux.Vars(r)["feedID"]
+	)
+
+	sub, err := s.repo.WebSubSubscription(ctx, feedID)
+	if errors.Is(err, seymour.ErrNotFound) {
+		return seyerrs.E("no subscription for feed", http.StatusNotFound)
+	}
+	if err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPushedBodySize))
+	if err != nil {
+		return seyerrs.E(err, http.StatusBadRequest)
+	}
+
+	// Hubs expect a success either way, content that isn't signed correctly is just ignored
+	if !sync.VerifySignature(r.Header.Get("X-Hub-Signature"), sub.Secret, body) {
+		slog.Warn("ignoring websub content with an invalid signature", "feed_id", feedID)
+		w.WriteHeader(http.StatusAccepted)
+		return nil
+	}
+
+	_, entries, err := sync.Parse(feedID, r.Header.Get("Content-Type"), body)
+	if err != nil {
+		return seyerrs.E(err, http.StatusBadRequest)
+	}
+	if err := s.repo.InsertEntries(ctx, entries); err != nil {
+		return err
+	}
+	if err := worker.TriggerRefreshTimelineWorkflow(ctx, s.tempCli); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
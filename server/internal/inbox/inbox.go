@@ -0,0 +1,214 @@
+// Package inbox receives email newsletters over SMTP, filing each message as an entry of the
+// newsletter feed it was addressed to.
+//
+// Every newsletter feed has its own address, <token>@ the inbox's domain, with the token kept in
+// the feed's url as newsletter:<token>. Mail to anything else is refused.
+package inbox
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-smtp"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+	"github.com/jdholdren/seymour/internal/sync"
+)
+
+// Defaults for the [Config] left unset.
+const (
+	DefaultDomain          = "inbox.local"
+	DefaultMaxMessageBytes = 10 << 20
+)
+
+// Config is where the inbox listens and what mail it takes.
+type Config struct {
+	Addr            string // e.g. :2525
+	Domain          string // The part of the feeds' addresses after the @
+	MaxMessageBytes int
+}
+
+// Store is what the inbox needs to file mail under its feed, e.g. a [seymour.Repository].
+type Store interface {
+	FeedByURL(ctx context.Context, url string) (seymour.Feed, error)
+	InsertEntries(ctx context.Context, entries []seymour.FeedEntry) error
+	UpdateFeed(ctx context.Context, id string, args seymour.UpdateFeedArgs) error
+}
+
+// Server is the SMTP listener for the inbox.
+type Server struct {
+	smtp *smtp.Server
+}
+
+// NewServer creates an inbox that files mail into the store, calling delivered after each
+// message so its entries make it to the timeline.
+func NewServer(cfg Config, store Store, delivered func(ctx context.Context) error) *Server {
+	domain := strings.ToLower(cmp.Or(cfg.Domain, DefaultDomain))
+
+	s := smtp.NewServer(backend{store: store, domain: domain, delivered: delivered})
+	s.Addr = cfg.Addr
+	s.Domain = domain
+	s.MaxMessageBytes = cmp.Or(cfg.MaxMessageBytes, DefaultMaxMessageBytes)
+	s.ReadTimeout = time.Minute
+	s.WriteTimeout = time.Minute
+	s.AuthDisabled = true // Newsletters are sent to us, no one sends as us
+
+	return &Server{smtp: s}
+}
+
+// ListenAndServe listens on the configured address until the server's closed.
+func (s *Server) ListenAndServe() error {
+	return s.smtp.ListenAndServe()
+}
+
+// Serve accepts mail on the listener until the server's closed.
+func (s *Server) Serve(l net.Listener) error {
+	return s.smtp.Serve(l)
+}
+
+// Close stops listening and drops any connections.
+func (s *Server) Close() error {
+	return s.smtp.Close()
+}
+
+// NewToken creates the token for a new newsletter feed's address.
+func NewToken() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating token: %w", err)
+	}
+
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)), nil
+}
+
+// FeedURL is the url of the newsletter feed with the token.
+func FeedURL(token string) string {
+	return string(seymour.FeedKindNewsletter) + ":" + token
+}
+
+// Address is where mail for the newsletter feed goes, empty if it isn't one.
+func Address(feed seymour.Feed, domain string) string {
+	token, ok := strings.CutPrefix(feed.URL, FeedURL(""))
+	if !ok || feed.Kind != seymour.FeedKindNewsletter {
+		return ""
+	}
+
+	return token + "@" + strings.ToLower(cmp.Or(domain, DefaultDomain))
+}
+
+type backend struct {
+	store     Store
+	domain    string
+	delivered func(ctx context.Context) error
+}
+
+func (b backend) Login(*smtp.ConnectionState, string, string) (smtp.Session, error) {
+	return nil, smtp.ErrAuthUnsupported
+}
+
+func (b backend) AnonymousLogin(*smtp.ConnectionState) (smtp.Session, error) {
+	return &session{backend: b}, nil
+}
+
+// session is a single SMTP conversation, which can deliver several messages.
+type session struct {
+	backend
+	feeds []seymour.Feed // Who the current message is for
+}
+
+var (
+	errNoMailbox = &smtp.SMTPError{Code: 550, EnhancedCode: smtp.EnhancedCode{5, 1, 1}, Message: "No such newsletter"}
+
+	// Tells the sender to hold on to the message and deliver it later
+	errTryAgain = &smtp.SMTPError{Code: 451, EnhancedCode: smtp.EnhancedCode{4, 3, 0}, Message: "Try again later"}
+)
+
+func (s *session) Mail(string, smtp.MailOptions) error { return nil }
+
+func (s *session) Rcpt(to string) error {
+	addr, err := mail.ParseAddress(to)
+	if err != nil {
+		return errNoMailbox
+	}
+	token, domain, _ := strings.Cut(strings.ToLower(addr.Address), "@")
+	if domain != s.domain || token == "" {
+		return errNoMailbox
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	feed, err := s.store.FeedByURL(ctx, FeedURL(token))
+	if errors.Is(err, seymour.ErrNotFound) || (err == nil && feed.Kind != seymour.FeedKindNewsletter) {
+		return errNoMailbox
+	}
+	if err != nil {
+		slog.Error("error looking up newsletter", "error", err)
+		return errTryAgain
+	}
+
+	s.feeds = append(s.feeds, feed)
+	return nil
+}
+
+func (s *session) Data(r io.Reader) error {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	for _, feed := range s.feeds {
+		entry, err := sync.ParseMail(feed.ID, bytes.NewReader(raw))
+		if err != nil {
+			return &smtp.SMTPError{Code: 554, EnhancedCode: smtp.EnhancedCode{5, 6, 0}, Message: "Message couldn't be read"}
+		}
+		if err := s.store.InsertEntries(ctx, []seymour.FeedEntry{entry}); err != nil {
+			slog.Error("error filing newsletter", "feed_id", feed.ID, "error", err)
+			return errTryAgain
+		}
+
+		// Delivery counts as the feed's sync, and a feed without a name takes the sender's
+		args := seymour.UpdateFeedArgs{
+			LastSynced:  seymour.DBTime{Time: now},
+			LastSuccess: seymour.DBTime{Time: now},
+		}
+		if (feed.Title == nil || *feed.Title == "") && len(entry.Authors) > 0 {
+			args.Title = entry.Authors[0]
+		}
+		if err := s.store.UpdateFeed(ctx, feed.ID, args); err != nil {
+			slog.Error("error updating newsletter", "feed_id", feed.ID, "error", err)
+		}
+	}
+
+	if s.delivered != nil {
+		if err := s.delivered(ctx); err != nil {
+			// The entries are in, the next refresh picks them up
+			slog.Error("error refreshing timeline after newsletter", "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *session) Reset() {
+	s.feeds = nil
+}
+
+func (s *session) Logout() error {
+	return nil
+}
@@ -0,0 +1,116 @@
+package inbox_test
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"strings"
+	gosync "sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jdholdren/seymour/internal/inbox"
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// store keeps what the inbox files in memory.
+type store struct {
+	mu      gosync.Mutex
+	feeds   map[string]seymour.Feed
+	entries []seymour.FeedEntry
+	updates map[string]seymour.UpdateFeedArgs
+}
+
+func (s *store) FeedByURL(_ context.Context, url string) (seymour.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feed, ok := s.feeds[url]
+	if !ok {
+		return seymour.Feed{}, seymour.ErrNotFound
+	}
+	return feed, nil
+}
+
+func (s *store) InsertEntries(_ context.Context, entries []seymour.FeedEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+func (s *store) UpdateFeed(_ context.Context, id string, args seymour.UpdateFeedArgs) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updates[id] = args
+	return nil
+}
+
+func TestServer(t *testing.T) {
+	token, err := inbox.NewToken()
+	require.NoError(t, err)
+
+	newsletter := seymour.Feed{ID: "feed-newsletter", URL: inbox.FeedURL(token), Kind: seymour.FeedKindNewsletter}
+	st := &store{
+		feeds: map[string]seymour.Feed{
+			newsletter.URL:                  newsletter,
+			inbox.FeedURL("notanewsletter"): {ID: "feed-other", URL: inbox.FeedURL("notanewsletter"), Kind: seymour.FeedKindFeed},
+		},
+		updates: map[string]seymour.UpdateFeedArgs{},
+	}
+	delivered := make(chan struct{}, 1)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := inbox.NewServer(inbox.Config{Domain: "Inbox.Local"}, st, func(context.Context) error {
+		delivered <- struct{}{}
+		return nil
+	})
+	go func() { _ = srv.Serve(l) }()
+	defer func() { _ = srv.Close() }()
+
+	address := inbox.Address(newsletter, "inbox.local")
+	assert.Equal(t, token+"@inbox.local", address)
+
+	msg := "From: \"The Weekly\" <hello@weekly.example>\r\n" +
+		"To: " + address + "\r\n" +
+		"Subject: Issue 42\r\n" +
+		"Message-ID: <issue-42@weekly.example>\r\n" +
+		"List-Unsubscribe: <https://weekly.example/unsub>\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n" +
+		"\r\n" +
+		"<p>This week's issue.</p>\r\n"
+	require.NoError(t, smtp.SendMail(l.Addr().String(), nil, "hello@weekly.example", []string{strings.ToUpper(token) + "@inbox.local"}, []byte(msg)))
+	<-delivered
+
+	require.Len(t, st.entries, 1)
+	entry := st.entries[0]
+	assert.Equal(t, "feed-newsletter", entry.FeedID)
+	assert.Equal(t, "issue-42@weekly.example", entry.GUID)
+	assert.Equal(t, "Issue 42", entry.Title)
+	assert.Equal(t, "<p>This week's issue.</p>", entry.Content)
+	assert.Equal(t, "<https://weekly.example/unsub>", entry.ListUnsubscribe)
+
+	// Delivery counts as a sync, and the unnamed feed is named after its sender
+	update := st.updates["feed-newsletter"]
+	assert.Equal(t, "The Weekly", update.Title)
+	assert.False(t, update.LastSynced.Time.IsZero())
+
+	t.Run("refuses other addresses", func(t *testing.T) {
+		for _, to := range []string{
+			"nosuchtoken@inbox.local",
+			token + "@elsewhere.example",
+			"notanewsletter@inbox.local",
+		} {
+			err := smtp.SendMail(l.Addr().String(), nil, "hello@weekly.example", []string{to}, []byte(msg))
+			assert.ErrorContains(t, err, "550", to)
+		}
+		assert.Len(t, st.entries, 1)
+	})
+
+	assert.Empty(t, inbox.Address(seymour.Feed{URL: "https://example.com/feed", Kind: seymour.FeedKindFeed}, "inbox.local"))
+}
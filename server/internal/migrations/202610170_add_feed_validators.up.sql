@@ -0,0 +1,4 @@
+-- HTTP cache validators from the last successful fetch, sent back as
+-- If-None-Match / If-Modified-Since so unchanged feeds can answer 304.
+ALTER TABLE feeds ADD COLUMN etag TEXT;
+ALTER TABLE feeds ADD COLUMN last_modified TEXT;
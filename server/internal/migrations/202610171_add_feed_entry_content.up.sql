@@ -0,0 +1,3 @@
+-- Full, sanitized HTML of an entry when the feed provides it.
+-- The description stays a short plain-text teaser.
+ALTER TABLE feed_entries ADD COLUMN content TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_entry_media_feed_entry_id;
+DROP TABLE IF EXISTS entry_media;
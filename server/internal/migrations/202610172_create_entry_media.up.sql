@@ -0,0 +1,13 @@
+-- Entry media table: attachments on feed entries, like podcast audio or videos
+CREATE TABLE entry_media (
+	id TEXT PRIMARY KEY,
+	feed_entry_id TEXT NOT NULL,
+	url TEXT NOT NULL,
+	mime_type TEXT NOT NULL DEFAULT '',
+	length INTEGER NOT NULL DEFAULT 0,
+	duration INTEGER NOT NULL DEFAULT 0,
+	thumbnail_url TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_entry_media_feed_entry_id ON entry_media(feed_entry_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_entry_categories_name;
+DROP INDEX IF EXISTS idx_entry_authors_name;
+DROP TABLE IF EXISTS entry_categories;
+DROP TABLE IF EXISTS entry_authors;
@@ -0,0 +1,17 @@
+-- Who wrote each feed entry
+CREATE TABLE entry_authors (
+	feed_entry_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	PRIMARY KEY (feed_entry_id, name)
+);
+
+-- What each feed entry is filed under
+CREATE TABLE entry_categories (
+	feed_entry_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	PRIMARY KEY (feed_entry_id, name)
+);
+
+-- For filtering the timeline by author or category
+CREATE INDEX idx_entry_authors_name ON entry_authors(name COLLATE NOCASE);
+CREATE INDEX idx_entry_categories_name ON entry_categories(name COLLATE NOCASE);
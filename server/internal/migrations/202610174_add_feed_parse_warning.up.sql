@@ -0,0 +1,2 @@
+-- Problems from the last sync that didn't stop it, like unparseable publish dates
+ALTER TABLE feeds ADD COLUMN parse_warning TEXT;
@@ -0,0 +1,20 @@
+-- Back to globally unique guids: entries whose guid collides with another feed's are dropped.
+DROP INDEX IF EXISTS idx_feed_entries_feed_id_guid;
+
+CREATE TABLE feed_entries_old (
+	id TEXT PRIMARY KEY,
+	feed_id TEXT NOT NULL,
+	title TEXT NOT NULL,
+	description TEXT NOT NULL,
+	guid TEXT NOT NULL UNIQUE,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	publish_time DATETIME NULL,
+	link VARCHAR(256) NOT NULL,
+	content TEXT NOT NULL DEFAULT ''
+);
+
+INSERT OR IGNORE INTO feed_entries_old (id, feed_id, title, description, guid, created_at, publish_time, link, content)
+SELECT id, feed_id, title, description, guid, created_at, publish_time, link, content FROM feed_entries;
+
+DROP TABLE feed_entries;
+ALTER TABLE feed_entries_old RENAME TO feed_entries;
@@ -0,0 +1,27 @@
+-- Entry guids are only unique within their feed: two feeds can both have an item "1".
+-- SQLite can't drop a column constraint, so the table is rebuilt without it.
+CREATE TABLE feed_entries_new (
+	id TEXT PRIMARY KEY,
+	feed_id TEXT NOT NULL,
+	title TEXT NOT NULL,
+	description TEXT NOT NULL,
+	guid TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	publish_time DATETIME NULL,
+	link VARCHAR(256) NOT NULL,
+	content TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO feed_entries_new (id, feed_id, title, description, guid, created_at, publish_time, link, content)
+SELECT id, feed_id, title, description, guid, created_at, publish_time, link, content FROM feed_entries;
+
+DROP TABLE feed_entries;
+ALTER TABLE feed_entries_new RENAME TO feed_entries;
+
+-- Backfill entries that were stored without a guid, matching what the sync synthesizes for them:
+-- link, title, and publish date joined by pipes.
+UPDATE feed_entries
+SET guid = link || '|' || title || '|' || COALESCE(publish_time, '')
+WHERE guid = '';
+
+CREATE UNIQUE INDEX idx_feed_entries_feed_id_guid ON feed_entries(feed_id, guid);
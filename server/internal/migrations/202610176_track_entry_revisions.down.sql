@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_entry_revisions_feed_entry_id;
+DROP TABLE IF EXISTS entry_revisions;
+ALTER TABLE feed_entries DROP COLUMN updated_at;
+ALTER TABLE feed_entries DROP COLUMN content_hash;
@@ -0,0 +1,18 @@
+-- Hash of an entry's title, description, link and content, to notice when the publisher changes it
+ALTER TABLE feed_entries ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+-- When a change was last noticed, null if it never changed
+ALTER TABLE feed_entries ADD COLUMN updated_at DATETIME NULL;
+
+-- Entry revisions table: the previous versions of entries that were changed by their feed
+CREATE TABLE entry_revisions (
+	id TEXT PRIMARY KEY,
+	feed_entry_id TEXT NOT NULL,
+	title TEXT NOT NULL,
+	description TEXT NOT NULL,
+	link VARCHAR(256) NOT NULL,
+	content TEXT NOT NULL,
+	content_hash TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_entry_revisions_feed_entry_id ON entry_revisions(feed_entry_id);
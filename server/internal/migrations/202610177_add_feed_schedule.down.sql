@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_feeds_next_sync_at;
+ALTER TABLE feeds DROP COLUMN sync_interval;
+ALTER TABLE feeds DROP COLUMN next_sync_at;
@@ -0,0 +1,7 @@
+-- When each feed is next due to be synced, worked out from how often it posts and what
+-- it asks for. Null means it's due now.
+ALTER TABLE feeds ADD COLUMN next_sync_at DATETIME;
+-- The seconds between syncs from the last full sync, reused when the feed answers 304
+ALTER TABLE feeds ADD COLUMN sync_interval INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_feeds_next_sync_at ON feeds(next_sync_at);
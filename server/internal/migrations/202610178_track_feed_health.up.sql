@@ -0,0 +1,7 @@
+-- Health of each feed's syncs, so failures aren't only in the logs. Feeds that keep
+-- failing are backed off and eventually marked broken until someone retries them.
+ALTER TABLE feeds ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE feeds ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN last_error TEXT;
+ALTER TABLE feeds ADD COLUMN last_error_at DATETIME;
+ALTER TABLE feeds ADD COLUMN last_success_at DATETIME;
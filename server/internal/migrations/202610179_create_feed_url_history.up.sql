@@ -0,0 +1,9 @@
+-- Feed url history table: the urls a feed lived at before it was permanently redirected,
+-- so subscribing to an old url still finds the feed
+CREATE TABLE feed_url_history (
+	url TEXT PRIMARY KEY,
+	feed_id TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_feed_url_history_feed_id ON feed_url_history(feed_id);
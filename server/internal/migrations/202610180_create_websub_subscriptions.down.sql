@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS websub_subscriptions;
+ALTER TABLE feeds DROP COLUMN topic_url;
+ALTER TABLE feeds DROP COLUMN hub_url;
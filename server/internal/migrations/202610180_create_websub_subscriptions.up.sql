@@ -0,0 +1,15 @@
+-- The WebSub hub a feed advertises and the url it knows the feed by
+ALTER TABLE feeds ADD COLUMN hub_url TEXT;
+ALTER TABLE feeds ADD COLUMN topic_url TEXT;
+
+-- WebSub subscriptions table: requests for hubs to push feed updates instead of waiting on a poll
+CREATE TABLE websub_subscriptions (
+	feed_id TEXT PRIMARY KEY,
+	hub_url TEXT NOT NULL,
+	topic_url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	state TEXT NOT NULL,
+	lease_expires_at DATETIME,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+-- Credentials for private feeds: basic auth, a bearer token, or extra headers and query
+-- parameters. Sealed with the credentials key from the environment, never stored in the clear.
+ALTER TABLE feeds ADD COLUMN credentials TEXT;
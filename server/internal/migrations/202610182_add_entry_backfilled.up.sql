@@ -0,0 +1,3 @@
+-- Entries pulled in from a feed's older pages or archives rather than synced as they were
+-- published. They're kept out of the timeline unless asked for.
+ALTER TABLE feed_entries ADD COLUMN backfilled INTEGER NOT NULL DEFAULT 0;
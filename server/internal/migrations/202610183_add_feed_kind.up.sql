@@ -0,0 +1,3 @@
+-- Where a feed's entries come from: an RSS, Atom, or JSON feed, or one of the sources
+-- like a GitHub repository's releases. Matches the scheme of the feed's url for sources.
+ALTER TABLE feeds ADD COLUMN kind TEXT NOT NULL DEFAULT 'feed';
@@ -0,0 +1,2 @@
+-- Settings for the sources that need them, as JSON, e.g. the selectors of a scraped feed.
+ALTER TABLE feeds ADD COLUMN source_config TEXT;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_page_snapshots_feed_id;
+DROP TABLE IF EXISTS page_snapshots;
@@ -0,0 +1,11 @@
+-- Page snapshots table: the readable text of watched pages, kept each time it changes
+CREATE TABLE page_snapshots (
+	id TEXT PRIMARY KEY,
+	feed_id TEXT NOT NULL,
+	title TEXT NOT NULL,
+	content TEXT NOT NULL,
+	content_hash TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_page_snapshots_feed_id ON page_snapshots(feed_id);
@@ -0,0 +1 @@
+ALTER TABLE feed_entries DROP COLUMN list_unsubscribe;
@@ -0,0 +1,2 @@
+-- The List-Unsubscribe header of entries that arrived as email newsletters, empty for everything else
+ALTER TABLE feed_entries ADD COLUMN list_unsubscribe TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Where a source left off, for the ones that can't tell from their latest entries, e.g. a sitemap.
+ALTER TABLE feeds ADD COLUMN source_cursor TEXT;
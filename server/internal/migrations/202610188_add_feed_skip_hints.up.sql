@@ -0,0 +1,4 @@
+-- The hours (in GMT) and weekdays an RSS feed asked not to be fetched in, comma separated,
+-- so syncs that find it unchanged keep to them too.
+ALTER TABLE feeds ADD COLUMN skip_hours TEXT;
+ALTER TABLE feeds ADD COLUMN skip_days TEXT;
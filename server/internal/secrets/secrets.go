@@ -0,0 +1,87 @@
+// Package secrets encrypts the things that are stored but mustn't be readable from the database,
+// like the credentials for private feeds.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrNoKey is returned when sealing or opening with a [Box] that wasn't given a key.
+var ErrNoKey = errors.New("no secrets key configured")
+
+// Box seals and opens secrets with AES-256-GCM.
+//
+// A nil box has no key, and errors with [ErrNoKey] instead.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox creates a box from a base64 encoded 32 byte key, e.g. from `openssl rand -base64 32`.
+//
+// An empty key gives a nil box.
+func NewBox(key string) (*Box, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding secrets key: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("secrets key must be 32 bytes, got %d", len(raw))
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gcm: %w", err)
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts the plaintext, returning it base64 encoded with its nonce.
+func (b *Box) Seal(plaintext []byte) (string, error) {
+	if b == nil {
+		return "", ErrNoKey
+	}
+
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("error generating nonce: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(b.aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// Open decrypts something from [Box.Seal].
+func (b *Box) Open(sealed string) ([]byte, error) {
+	if b == nil {
+		return nil, ErrNoKey
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding sealed secret: %w", err)
+	}
+	if len(raw) < b.aead.NonceSize() {
+		return nil, errors.New("sealed secret is too short")
+	}
+
+	nonce, ciphertext := raw[:b.aead.NonceSize()], raw[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error opening sealed secret: %w", err)
+	}
+
+	return plaintext, nil
+}
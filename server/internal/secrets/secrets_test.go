@@ -0,0 +1,65 @@
+package secrets_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jdholdren/seymour/internal/secrets"
+)
+
+var testKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+
+func TestBox_RoundTrip(t *testing.T) {
+	box, err := secrets.NewBox(testKey)
+	require.NoError(t, err)
+
+	sealed, err := box.Seal([]byte("hunter2"))
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "hunter2")
+
+	// A fresh nonce every time
+	again, err := box.Seal([]byte("hunter2"))
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again)
+
+	opened, err := box.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", string(opened))
+}
+
+func TestBox_WrongKeyOrTampered(t *testing.T) {
+	box, err := secrets.NewBox(testKey)
+	require.NoError(t, err)
+	other, err := secrets.NewBox(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 32))))
+	require.NoError(t, err)
+
+	sealed, err := box.Seal([]byte("hunter2"))
+	require.NoError(t, err)
+
+	_, err = other.Open(sealed)
+	assert.Error(t, err)
+
+	raw, _ := base64.StdEncoding.DecodeString(sealed)
+	raw[len(raw)-1] ^= 0xff
+	_, err = box.Open(base64.StdEncoding.EncodeToString(raw))
+	assert.Error(t, err)
+}
+
+func TestNewBox(t *testing.T) {
+	box, err := secrets.NewBox("")
+	require.NoError(t, err)
+	assert.Nil(t, box)
+
+	_, err = box.Seal([]byte("hunter2"))
+	assert.ErrorIs(t, err, secrets.ErrNoKey)
+
+	_, err = secrets.NewBox(base64.StdEncoding.EncodeToString([]byte("too short")))
+	assert.Error(t, err)
+
+	_, err = secrets.NewBox("not base64!")
+	assert.Error(t, err)
+}
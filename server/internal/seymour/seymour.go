@@ -15,15 +15,28 @@ type Repository interface {
 	Feed(ctx context.Context, id string) (Feed, error)
 	Feeds(ctx context.Context, ids []string) ([]Feed, error)
 	FeedByURL(ctx context.Context, url string) (Feed, error)
-	InsertFeed(ctx context.Context, url string) (Feed, error)
+	MoveFeed(ctx context.Context, id, url string) (string, error)
+	InsertFeed(ctx context.Context, url string, kind FeedKind) (Feed, error)
 	DeleteFeed(ctx context.Context, id string) error
-	CountAllFeeds(ctx context.Context) (int, error)
-	FeedIDs(ctx context.Context, offset, pageSize int) ([]string, error)
+	DueFeedIDs(ctx context.Context, now time.Time, after string, limit int) ([]string, error)
 	Entry(ctx context.Context, id string) (FeedEntry, error)
 	Entries(ctx context.Context, ids []string) ([]FeedEntry, error)
 	InsertEntries(ctx context.Context, entries []FeedEntry) error
+	SetEntrySummary(ctx context.Context, id, title, description string) error
+	EntryRevisions(ctx context.Context, entryID string) ([]EntryRevision, error)
 	UpdateFeed(ctx context.Context, id string, args UpdateFeedArgs) error
 
+	// Snapshot operations, for watched pages
+	Snapshot(ctx context.Context, id string) (PageSnapshot, error)
+	PreviousSnapshot(ctx context.Context, id string) (PageSnapshot, error)
+	LatestSnapshot(ctx context.Context, feedID string) (PageSnapshot, error)
+	InsertSnapshot(ctx context.Context, snapshot PageSnapshot) (PageSnapshot, error)
+
+	// WebSub operations
+	WebSubSubscription(ctx context.Context, feedID string) (WebSubSubscription, error)
+	UpsertWebSubSubscription(ctx context.Context, sub WebSubSubscription) error
+	FeedsNeedingWebSub(ctx context.Context, renewBefore, retryBefore time.Time) ([]string, error)
+
 	// Prompt operations
 	ActivePrompt(ctx context.Context) (*Prompt, error)
 	SetPrompt(ctx context.Context, content string) (Prompt, error)
@@ -49,15 +62,142 @@ type Prompt struct {
 
 // Feed represents an RSS feed's details.
 type Feed struct {
-	ID           string  `db:"id"`
-	Title        *string `db:"title"`
-	URL          string  `db:"url"`
-	Description  *string `db:"description"`
-	LastSyncedAt *DBTime `db:"last_synced_at"`
-	CreatedAt    DBTime  `db:"created_at"`
-	UpdatedAt    DBTime  `db:"updated_at"`
+	ID           string   `db:"id"`
+	Title        *string  `db:"title"`
+	URL          string   `db:"url"`
+	Kind         FeedKind `db:"kind"`
+	Description  *string  `db:"description"`
+	LastSyncedAt *DBTime  `db:"last_synced_at"`
+	CreatedAt    DBTime   `db:"created_at"`
+	UpdatedAt    DBTime   `db:"updated_at"`
+
+	// HTTP validators from the last fetch, used for conditional requests.
+	ETag         *string `db:"etag"`
+	LastModified *string `db:"last_modified"`
+
+	// Problems from the last sync that didn't stop it, like unparseable dates.
+	ParseWarning *string `db:"parse_warning"`
+
+	// Where the feed's WebSub hub is, and the url it knows the feed by. Nil if it doesn't have one.
+	HubURL   *string `db:"hub_url"`
+	TopicURL *string `db:"topic_url"`
+
+	// When the feed is due to be synced again, nil if it's due now.
+	NextSyncAt *DBTime `db:"next_sync_at"`
+	// Seconds between syncs worked out from the last full sync, zero if unknown.
+	SyncInterval int64 `db:"sync_interval"`
+	// The hours, in GMT, and weekdays the feed asked not to be fetched in, comma separated,
+	// e.g. "0,1,2" and "Saturday,Sunday". Nil if it never said.
+	SkipHours *string `db:"skip_hours"`
+	SkipDays  *string `db:"skip_days"`
+
+	// Health of the feed's syncs. Broken feeds aren't synced until they're retried.
+	Status              FeedStatus `db:"status"`
+	ConsecutiveFailures int        `db:"consecutive_failures"`
+	LastError           *string    `db:"last_error"`
+	LastErrorAt         *DBTime    `db:"last_error_at"`
+	LastSuccessAt       *DBTime    `db:"last_success_at"`
+
+	// What a private feed needs to be fetched, sealed as JSON [FeedCredentials].
+	// Nil for public feeds. Never sent back out of the API.
+	Credentials *string `db:"credentials"`
+
+	// Settings for the feed's source as JSON, e.g. a scraped feed's [ScrapeConfig]. Nil if it doesn't need any.
+	SourceConfig *string `db:"source_config"`
+	// Where the feed's source left off last sync, e.g. how far through a sitemap it's read. Nil if it doesn't keep track.
+	SourceCursor *string `db:"source_cursor"`
+}
+
+// FeedCredentials are what's sent along with the requests for a private feed.
+type FeedCredentials struct {
+	// HTTP basic auth
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// Sent as a bearer token, e.g. for an API's activity feed
+	Token string `json:"token,omitempty"`
+
+	// Anything else, e.g. an API key header, or a token some newsletters expect in the url
+	Headers map[string]string `json:"headers,omitempty"`
+	Query   map[string]string `json:"query,omitempty"`
+}
+
+// IsZero checks if there aren't any credentials at all.
+func (c FeedCredentials) IsZero() bool {
+	return c.Username == "" && c.Password == "" && c.Token == "" && len(c.Headers) == 0 && len(c.Query) == 0
 }
 
+// ScrapeConfig picks the entries of a scraped feed out of its page with CSS selectors.
+//
+// Everything but Item is matched within each item. Without a title selector, the item's own
+// text is its title, and without a link selector, its first link is used.
+type ScrapeConfig struct {
+	Item    string `json:"item"`
+	Title   string `json:"title,omitempty"`
+	Link    string `json:"link,omitempty"`
+	Date    string `json:"date,omitempty"` // A <time>'s datetime is used over its text
+	Summary string `json:"summary,omitempty"`
+}
+
+// WatchConfig is how a watched page is compared from one sync to the next.
+type WatchConfig struct {
+	// CSS selectors for the parts of the page that change without mattering, like timestamps
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+// PageSnapshot is the readable text of a watched page, kept each time it changes.
+type PageSnapshot struct {
+	ID          string `db:"id"`
+	FeedID      string `db:"feed_id"`
+	Title       string `db:"title"`
+	Content     string `db:"content"` // Normalized to a line per paragraph, heading, list item, etc.
+	ContentHash string `db:"content_hash"`
+	CreatedAt   DBTime `db:"created_at"`
+}
+
+// WebSubSubscription is a request for a feed's hub to push updates to us.
+type WebSubSubscription struct {
+	FeedID         string      `db:"feed_id"`
+	HubURL         string      `db:"hub_url"`
+	TopicURL       string      `db:"topic_url"`
+	Secret         string      `db:"secret"` // For checking the signatures of pushed content
+	State          WebSubState `db:"state"`
+	LeaseExpiresAt *DBTime     `db:"lease_expires_at"` // Nil until the hub verifies it
+	CreatedAt      DBTime      `db:"created_at"`
+	UpdatedAt      DBTime      `db:"updated_at"`
+}
+
+// WebSubState is where a WebSub subscription is at with its hub.
+type WebSubState string
+
+const (
+	WebSubStatePending  WebSubState = "pending"  // Requested, waiting on the hub to verify it
+	WebSubStateVerified WebSubState = "verified" // The hub is pushing updates until the lease expires
+	WebSubStateDenied   WebSubState = "denied"   // The hub refused it
+)
+
+// FeedKind is where a feed's entries come from.
+//
+// Besides actual feeds, it's the kind of a source from the sync package, e.g. "github".
+type FeedKind string
+
+const (
+	FeedKindFeed  FeedKind = "feed"  // RSS, Atom, or JSON Feed
+	FeedKindWatch FeedKind = "watch" // A page whose changes are the entries
+
+	// Emails delivered to the inbox, never fetched. Their urls are newsletter:<token>,
+	// with mail to <token>@ the inbox's domain landing in them.
+	FeedKindNewsletter FeedKind = "newsletter"
+)
+
+// FeedStatus is whether a feed is still being synced.
+type FeedStatus string
+
+const (
+	FeedStatusActive FeedStatus = "active"
+	FeedStatusBroken FeedStatus = "broken" // Failed too many times in a row
+)
+
 // FeedEntry represents a unique entry in an RSS feed.
 type FeedEntry struct {
 	ID          string `db:"id"`
@@ -68,13 +208,76 @@ type FeedEntry struct {
 	CreatedAt   DBTime `db:"created_at"`
 	PublishTime DBTime `db:"publish_time"`
 	Link        string `db:"link"`
+
+	// The full, sanitized HTML of the entry if the feed includes it.
+	Content string `db:"content"`
+
+	// For noticing when the feed changes an entry after it was first synced.
+	ContentHash string  `db:"content_hash"`
+	UpdatedAt   *DBTime `db:"updated_at"` // Nil if it was never changed
+
+	// Set when the entry came from the feed's older pages or archives instead of a sync.
+	Backfilled bool `db:"backfilled"`
+
+	// For newsletters, how to unsubscribe as the email's List-Unsubscribe header had it.
+	ListUnsubscribe string `db:"list_unsubscribe"`
+
+	// Attachments like podcast audio or videos. Stored in their own table.
+	Media []EntryMedia `db:"-"`
+
+	// Who wrote the entry and what it's filed under. Stored in their own tables.
+	Authors    []string `db:"-"`
+	Categories []string `db:"-"`
+}
+
+// EntryRevision is a previous version of a feed entry, kept when the feed changes it.
+type EntryRevision struct {
+	ID          string `db:"id"`
+	FeedEntryID string `db:"feed_entry_id"`
+	Title       string `db:"title"`
+	Description string `db:"description"`
+	Link        string `db:"link"`
+	Content     string `db:"content"`
+	ContentHash string `db:"content_hash"`
+	CreatedAt   DBTime `db:"created_at"` // When it was replaced
+}
+
+// EntryMedia is an attachment to a feed entry, like a podcast episode or a video.
+type EntryMedia struct {
+	ID           string `db:"id"`
+	FeedEntryID  string `db:"feed_entry_id"`
+	URL          string `db:"url"`
+	MIMEType     string `db:"mime_type"`
+	Length       int64  `db:"length"`   // In bytes, zero if unknown
+	Duration     int64  `db:"duration"` // In seconds, zero if unknown
+	ThumbnailURL string `db:"thumbnail_url"`
+	CreatedAt    DBTime `db:"created_at"`
 }
 
 // UpdateFeedArgs holds the optional fields for updating a feed.
 type UpdateFeedArgs struct {
-	Title       string
-	Description string
-	LastSynced  DBTime
+	Title        string
+	Description  string
+	LastSynced   DBTime
+	ETag         string
+	LastModified string
+	ParseWarning *string // Nil leaves it as is, empty clears it
+	NextSync     DBTime
+	SyncInterval int64   // In seconds
+	SkipHours    *string // Nil leaves it as is, empty clears it
+	SkipDays     *string // Nil leaves it as is, empty clears it
+	HubURL       *string // Nil leaves it as is, empty clears it
+	TopicURL     *string // Nil leaves it as is, empty clears it
+
+	Status              FeedStatus
+	ConsecutiveFailures *int // Nil leaves it as is
+	LastError           string
+	LastErrorAt         DBTime
+	LastSuccess         DBTime
+
+	Credentials  *string // Sealed, nil leaves them as is, empty clears them
+	SourceConfig *string // Nil leaves it as is, empty clears it
+	SourceCursor *string // Nil leaves it as is, empty clears it
 }
 
 // Subscription represents a subscription to a feed.
@@ -105,7 +308,14 @@ type MissingEntry struct {
 type TimelineEntriesArgs struct {
 	Status TimelineEntryStatus // To optionally filter by status
 	FeedID string              // To optionally filter by feed
-	Limit  uint64              // To optionally limit the number of entries returned
+
+	// To optionally filter by who wrote the entry or what it's filed under, case insensitive
+	Author   string
+	Category string
+	Limit    uint64 // To optionally limit the number of entries returned
+
+	// Backfilled entries are left out unless this is set
+	IncludeBackfilled bool
 
 	// Pagination fields
 	Offset uint64 // Offset for pagination
@@ -2,20 +2,27 @@ package sqlite
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"modernc.org/sqlite"
 
 	"github.com/jdholdren/seymour/internal/seymour"
 )
 
 const (
-	feedNamespace  = "-fd"
-	entryNamespace = "-ntry"
+	feedNamespace     = "-fd"
+	entryNamespace    = "-ntry"
+	mediaNamespace    = "-media"
+	revisionNamespace = "-rev"
 )
 
 func (r Repo) Feed(ctx context.Context, id string) (seymour.Feed, error) {
@@ -50,11 +57,20 @@ func (r Repo) Feeds(ctx context.Context, ids []string) ([]seymour.Feed, error) {
 	return feeds, nil
 }
 
+// FeedByURL finds the feed at the url, or the one that was there before it moved.
 func (r Repo) FeedByURL(ctx context.Context, url string) (seymour.Feed, error) {
-	const q = `SELECT * FROM feeds WHERE url = ?;`
+	const (
+		q        = `SELECT * FROM feeds WHERE url = ?;`
+		historyQ = `SELECT feeds.* FROM feeds
+		JOIN feed_url_history ON feed_url_history.feed_id = feeds.id
+		WHERE feed_url_history.url = ?;`
+	)
 
 	var feed seymour.Feed
 	err := r.db.GetContext(ctx, &feed, q, url)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = r.db.GetContext(ctx, &feed, historyQ, url)
+	}
 	if errors.Is(err, sql.ErrNoRows) {
 		return seymour.Feed{}, seymour.ErrNotFound
 	}
@@ -65,11 +81,99 @@ func (r Repo) FeedByURL(ctx context.Context, url string) (seymour.Feed, error) {
 	return feed, nil
 }
 
-func (r Repo) InsertFeed(ctx context.Context, url string) (seymour.Feed, error) {
-	const q = `INSERT INTO feeds (id, url) VALUES (:id, :url);`
+// MoveFeed points the feed at the url it permanently moved to, keeping the old one in its history.
+//
+// If another feed already lives at the new url, the two are merged: the other feed is kept,
+// and this one's subscription, history, any entries it doesn't already have, and its credentials
+// and source settings if the other has none are moved over before it's deleted. Returns the ID of the feed that's at the url afterwards.
+func (r Repo) MoveFeed(ctx context.Context, id, url string) (string, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var feed seymour.Feed
+	if err := tx.GetContext(ctx, &feed, `SELECT * FROM feeds WHERE id = ?;`, id); err != nil {
+		return "", fmt.Errorf("error fetching feed: %s", err)
+	}
+
+	var targetID string
+	err = tx.GetContext(ctx, &targetID, `SELECT id FROM feeds WHERE url = ? AND id != ?;`, url, id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("error fetching feed at new url: %s", err)
+	}
+
+	const historyQ = `INSERT OR REPLACE INTO feed_url_history (url, feed_id) VALUES (?, ?);`
+
+	// Nothing there yet, so the feed just moves
+	if targetID == "" {
+		if _, err := tx.ExecContext(ctx, historyQ, feed.URL, id); err != nil {
+			return "", fmt.Errorf("error recording feed url history: %s", err)
+		}
+		// The new url is now current, not history
+		if _, err := tx.ExecContext(ctx, `DELETE FROM feed_url_history WHERE url = ?;`, url); err != nil {
+			return "", fmt.Errorf("error clearing feed url history: %s", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE feeds SET url = ? WHERE id = ?;`, url, id); err != nil {
+			return "", fmt.Errorf("error updating feed url: %s", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return "", fmt.Errorf("error committing feed move: %w", err)
+		}
+		return id, nil
+	}
+
+	// Entries the other feed already has are dropped along with everything hanging off of them
+	const duplicatesQ = `SELECT id FROM feed_entries WHERE feed_id = ? AND guid IN (SELECT guid FROM feed_entries WHERE feed_id = ?)`
+	for _, table := range []string{"timeline_entries", "entry_media", "entry_authors", "entry_categories", "entry_revisions"} {
+		q := fmt.Sprintf(`DELETE FROM %s WHERE feed_entry_id IN (%s);`, table, duplicatesQ)
+		if _, err := tx.ExecContext(ctx, q, id, targetID); err != nil {
+			return "", fmt.Errorf("error deleting duplicate entry details from %s: %s", table, err)
+		}
+	}
+
+	stmts := []struct {
+		desc  string
+		query string
+		args  []any
+	}{
+		{"deleting duplicate entries", `DELETE FROM feed_entries WHERE id IN (` + duplicatesQ + `);`, []any{id, targetID}},
+		{"moving entries", `UPDATE feed_entries SET feed_id = ? WHERE feed_id = ?;`, []any{targetID, id}},
+		{"moving timeline entries", `UPDATE timeline_entries SET feed_id = ? WHERE feed_id = ?;`, []any{targetID, id}},
+		// There's at most one subscription per feed
+		{"moving subscription", `UPDATE OR IGNORE subscriptions SET feed_id = ? WHERE feed_id = ?;`, []any{targetID, id}},
+		{"deleting duplicate subscription", `DELETE FROM subscriptions WHERE feed_id = ?;`, []any{id}},
+		{"moving url history", `UPDATE feed_url_history SET feed_id = ? WHERE feed_id = ?;`, []any{targetID, id}},
+		{"recording feed url history", historyQ, []any{feed.URL, targetID}},
+		// Whatever the feed needed to be fetched, unless the kept feed has its own
+		{"carrying over fetch settings", `UPDATE feeds
+		SET credentials = COALESCE(NULLIF(credentials, ''), ?), source_config = COALESCE(NULLIF(source_config, ''), ?)
+		WHERE id = ?;`, []any{feed.Credentials, feed.SourceConfig, targetID}},
+		// The kept feed has its own hub subscription, if any
+		{"deleting merged websub subscription", `DELETE FROM websub_subscriptions WHERE feed_id = ?;`, []any{id}},
+		{"deleting merged feed", `DELETE FROM feeds WHERE id = ?;`, []any{id}},
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return "", fmt.Errorf("error %s: %s", stmt.desc, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("error committing feed merge: %w", err)
+	}
+
+	return targetID, nil
+}
+
+func (r Repo) InsertFeed(ctx context.Context, url string, kind seymour.FeedKind) (seymour.Feed, error) {
+	const q = `INSERT INTO feeds (id, url, kind) VALUES (:id, :url, :kind);`
 	f := seymour.Feed{
-		ID:  fmt.Sprintf("%s%s", uuid.NewString(), feedNamespace),
-		URL: url,
+		ID:   fmt.Sprintf("%s%s", uuid.NewString(), feedNamespace),
+		URL:  url,
+		Kind: kind,
 	}
 	_, err := r.db.NamedExecContext(ctx, q, f)
 	if sqliteErr := (&sqlite.Error{}); errors.As(err, &sqliteErr) && sqliteErr.Code() == 2067 {
@@ -83,11 +187,25 @@ func (r Repo) InsertFeed(ctx context.Context, url string) (seymour.Feed, error)
 }
 
 func (r Repo) DeleteFeed(ctx context.Context, id string) error {
-	const q = `DELETE FROM feeds WHERE id = ?;`
+	const (
+		q         = `DELETE FROM feeds WHERE id = ?;`
+		historyQ  = `DELETE FROM feed_url_history WHERE feed_id = ?;`
+		webSubQ   = `DELETE FROM websub_subscriptions WHERE feed_id = ?;`
+		snapshotQ = `DELETE FROM page_snapshots WHERE feed_id = ?;`
+	)
 
 	if _, err := r.db.ExecContext(ctx, q, id); err != nil {
 		return fmt.Errorf("error deleting feed: %s", err)
 	}
+	if _, err := r.db.ExecContext(ctx, historyQ, id); err != nil {
+		return fmt.Errorf("error deleting feed url history: %s", err)
+	}
+	if _, err := r.db.ExecContext(ctx, webSubQ, id); err != nil {
+		return fmt.Errorf("error deleting websub subscription: %s", err)
+	}
+	if _, err := r.db.ExecContext(ctx, snapshotQ, id); err != nil {
+		return fmt.Errorf("error deleting page snapshots: %s", err)
+	}
 
 	return nil
 }
@@ -104,25 +222,20 @@ func (r Repo) AllFeeds(ctx context.Context) ([]seymour.Feed, error) {
 	return feeds, nil
 }
 
-// CountAllFeeds returns the total number of feeds in the database.
-func (r Repo) CountAllFeeds(ctx context.Context) (int, error) {
-	const q = "SELECT COUNT(*) FROM feeds;"
-
-	var count int
-	if err := r.db.GetContext(ctx, &count, q); err != nil {
-		return 0, fmt.Errorf("error counting feeds: %s", err)
-	}
-
-	return count, nil
-}
-
-// FeedIDs returns a paginated list of feed IDs.
-func (r Repo) FeedIDs(ctx context.Context, offset, pageSize int) ([]string, error) {
-	const q = "SELECT id FROM feeds LIMIT ? OFFSET ?;"
+// DueFeedIDs returns a page of the IDs of feeds that are due to be synced, ordered by ID.
+// Broken feeds are never due, and newsletters are delivered rather than synced.
+//
+// Pages are keyed off of the last ID of the previous page rather than an offset, since
+// syncing feeds moves them out of the set while it's being paged through.
+func (r Repo) DueFeedIDs(ctx context.Context, now time.Time, after string, limit int) ([]string, error) {
+	const q = `SELECT id FROM feeds
+		WHERE (next_sync_at IS NULL OR next_sync_at <= ?) AND status != 'broken' AND kind != 'newsletter' AND id > ?
+		ORDER BY id
+		LIMIT ?;`
 
 	var ids []string
-	if err := r.db.SelectContext(ctx, &ids, q, pageSize, offset); err != nil {
-		return nil, fmt.Errorf("error fetching feed IDs: %s", err)
+	if err := r.db.SelectContext(ctx, &ids, q, seymour.DBTime{Time: now.UTC()}, after, limit); err != nil {
+		return nil, fmt.Errorf("error fetching due feed IDs: %s", err)
 	}
 
 	return ids, nil
@@ -158,29 +271,232 @@ func (r Repo) Entries(ctx context.Context, ids []string) ([]seymour.FeedEntry, e
 		return nil, fmt.Errorf("error fetching entries: %s", err)
 	}
 
+	if err := r.loadEntryDetails(ctx, entries, ids); err != nil {
+		return nil, err
+	}
+
 	return entries, nil
 }
 
+// loadEntryDetails fills in the media, authors, and categories of the entries from their own tables.
+func (r Repo) loadEntryDetails(ctx context.Context, entries []seymour.FeedEntry, ids []string) error {
+	query, args, err := sq.Select("*").From("entry_media").Where(sq.Eq{"feed_entry_id": ids}).ToSql()
+	if err != nil {
+		return fmt.Errorf("error constructing sql: %s", err)
+	}
+	var media []seymour.EntryMedia
+	if err := r.db.SelectContext(ctx, &media, query, args...); err != nil {
+		return fmt.Errorf("error fetching entry media: %s", err)
+	}
+
+	// Authors and categories have the same shape, so they're fetched the same way
+	type entryName struct {
+		FeedEntryID string `db:"feed_entry_id"`
+		Name        string `db:"name"`
+	}
+	namesByEntry := func(table string) (map[string][]string, error) {
+		query, args, err := sq.Select("feed_entry_id", "name").From(table).Where(sq.Eq{"feed_entry_id": ids}).ToSql()
+		if err != nil {
+			return nil, fmt.Errorf("error constructing sql: %s", err)
+		}
+		var rows []entryName
+		if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+			return nil, fmt.Errorf("error fetching from %s: %s", table, err)
+		}
+
+		ret := make(map[string][]string)
+		for _, row := range rows {
+			ret[row.FeedEntryID] = append(ret[row.FeedEntryID], row.Name)
+		}
+		return ret, nil
+	}
+	authors, err := namesByEntry("entry_authors")
+	if err != nil {
+		return err
+	}
+	categories, err := namesByEntry("entry_categories")
+	if err != nil {
+		return err
+	}
+
+	mediaByEntry := make(map[string][]seymour.EntryMedia)
+	for _, m := range media {
+		mediaByEntry[m.FeedEntryID] = append(mediaByEntry[m.FeedEntryID], m)
+	}
+	for i, entry := range entries {
+		entries[i].Media = mediaByEntry[entry.ID]
+		entries[i].Authors = authors[entry.ID]
+		entries[i].Categories = categories[entry.ID]
+	}
+
+	return nil
+}
+
+// InsertEntries adds any new entries along with their details.
+//
+// Entries that were already synced are compared by their content hash, and if the
+// feed changed them, the previous version is kept as a revision before updating.
 func (r Repo) InsertEntries(ctx context.Context, entries []seymour.FeedEntry) error {
 	if len(entries) == 0 {
 		return nil
 	}
 
-	// Create id's for the entries
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	const entryQ = `INSERT INTO feed_entries (id, feed_id, title, description, guid, link, publish_time, content, content_hash, backfilled, list_unsubscribe)
+	VALUES (:id, :feed_id, :title, :description, :guid, :link, :publish_time, :content, :content_hash, :backfilled, :list_unsubscribe)
+	ON CONFLICT(feed_id, guid) DO NOTHING;`
 	for i := range entries {
+		// Create an id for the entry
 		entries[i].ID = fmt.Sprintf("%s%s", uuid.New().String(), entryNamespace)
+		entries[i].ContentHash = entryHash(entries[i])
+
+		res, err := tx.NamedExecContext(ctx, entryQ, entries[i])
+		if err != nil {
+			return fmt.Errorf("error inserting entry: %s", err)
+		}
+
+		// Already synced, and its details along with it, but it might have changed
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			if err := updateChangedEntry(ctx, tx, &entries[i]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := insertEntryDetails(ctx, tx, entries[i]); err != nil {
+			return err
+		}
 	}
 
-	const q = `INSERT INTO feed_entries (id, feed_id, title, description, guid, link, publish_time)
-	VALUES (:id, :feed_id, :title, :description, :guid, :link, :publish_time)
-	ON CONFLICT(guid) DO NOTHING;`
-	if _, err := r.db.NamedExecContext(ctx, q, entries); err != nil {
-		return fmt.Errorf("error inserting entries; %s", err)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing entries: %w", err)
 	}
 
 	return nil
 }
 
+// updateChangedEntry compares an already synced entry to what's stored, and if it
+// changed, keeps the stored version as a revision and updates it.
+func updateChangedEntry(ctx context.Context, tx *sqlx.Tx, entry *seymour.FeedEntry) error {
+	const selectQ = `SELECT * FROM feed_entries WHERE feed_id = ? AND guid = ?;`
+	var existing seymour.FeedEntry
+	if err := tx.GetContext(ctx, &existing, selectQ, entry.FeedID, entry.GUID); err != nil {
+		return fmt.Errorf("error fetching existing entry: %s", err)
+	}
+	entry.ID = existing.ID
+
+	switch existing.ContentHash {
+	case entry.ContentHash:
+		return nil
+	case "":
+		// Synced before hashes were kept: nothing to compare against, so just start tracking
+		const hashQ = `UPDATE feed_entries SET content_hash = ? WHERE id = ?;`
+		if _, err := tx.ExecContext(ctx, hashQ, entry.ContentHash, existing.ID); err != nil {
+			return fmt.Errorf("error setting entry hash: %s", err)
+		}
+		return nil
+	}
+
+	const revisionQ = `INSERT INTO entry_revisions (id, feed_entry_id, title, description, link, content, content_hash)
+	VALUES (?, ?, ?, ?, ?, ?, ?);`
+	revisionID := fmt.Sprintf("%s%s", uuid.New().String(), revisionNamespace)
+	if _, err := tx.ExecContext(ctx, revisionQ,
+		revisionID, existing.ID, existing.Title, existing.Description, existing.Link, existing.Content, existing.ContentHash,
+	); err != nil {
+		return fmt.Errorf("error inserting entry revision: %s", err)
+	}
+
+	const updateQ = `UPDATE feed_entries
+	SET title = :title, description = :description, link = :link, content = :content, content_hash = :content_hash, updated_at = :updated_at
+	WHERE id = :id;`
+	entry.UpdatedAt = &seymour.DBTime{Time: time.Now().UTC()}
+	if _, err := tx.NamedExecContext(ctx, updateQ, entry); err != nil {
+		return fmt.Errorf("error updating entry: %s", err)
+	}
+
+	// Its attachments, authors, and categories might have been corrected along with it
+	for _, q := range []string{
+		`DELETE FROM entry_media WHERE feed_entry_id = ?;`,
+		`DELETE FROM entry_authors WHERE feed_entry_id = ?;`,
+		`DELETE FROM entry_categories WHERE feed_entry_id = ?;`,
+	} {
+		if _, err := tx.ExecContext(ctx, q, existing.ID); err != nil {
+			return fmt.Errorf("error clearing entry details: %s", err)
+		}
+	}
+
+	return insertEntryDetails(ctx, tx, *entry)
+}
+
+// insertEntryDetails stores the entry's media, authors, and categories, which have tables of their own.
+func insertEntryDetails(ctx context.Context, tx *sqlx.Tx, entry seymour.FeedEntry) error {
+	const (
+		mediaQ = `INSERT INTO entry_media (id, feed_entry_id, url, mime_type, length, duration, thumbnail_url)
+		VALUES (:id, :feed_entry_id, :url, :mime_type, :length, :duration, :thumbnail_url);`
+		authorQ   = `INSERT OR IGNORE INTO entry_authors (feed_entry_id, name) VALUES (?, ?);`
+		categoryQ = `INSERT OR IGNORE INTO entry_categories (feed_entry_id, name) VALUES (?, ?);`
+	)
+	for _, m := range entry.Media {
+		m.ID = fmt.Sprintf("%s%s", uuid.New().String(), mediaNamespace)
+		m.FeedEntryID = entry.ID
+		if _, err := tx.NamedExecContext(ctx, mediaQ, m); err != nil {
+			return fmt.Errorf("error inserting entry media: %s", err)
+		}
+	}
+	for _, author := range entry.Authors {
+		if _, err := tx.ExecContext(ctx, authorQ, entry.ID, author); err != nil {
+			return fmt.Errorf("error inserting entry author: %s", err)
+		}
+	}
+	for _, category := range entry.Categories {
+		if _, err := tx.ExecContext(ctx, categoryQ, entry.ID, category); err != nil {
+			return fmt.Errorf("error inserting entry category: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// entryHash summarizes the parts of an entry a publisher might correct.
+func entryHash(entry seymour.FeedEntry) string {
+	h := sha256.New()
+	for _, field := range []string{entry.Title, entry.Description, entry.Link, entry.Content} {
+		_, _ = io.WriteString(h, field)
+		_, _ = h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SetEntrySummary fills in the title and description of an entry that was synced without them,
+// like a sitemap's pages. It isn't the feed changing the entry, so its hash is left as it was.
+func (r Repo) SetEntrySummary(ctx context.Context, id, title, description string) error {
+	const q = `UPDATE feed_entries SET title = ?, description = ? WHERE id = ?;`
+
+	if _, err := r.db.ExecContext(ctx, q, title, description, id); err != nil {
+		return fmt.Errorf("error setting entry summary: %s", err)
+	}
+
+	return nil
+}
+
+// EntryRevisions returns the previous versions of an entry, most recently replaced first.
+func (r Repo) EntryRevisions(ctx context.Context, entryID string) ([]seymour.EntryRevision, error) {
+	const q = `SELECT * FROM entry_revisions WHERE feed_entry_id = ? ORDER BY created_at DESC;`
+
+	revisions := []seymour.EntryRevision{}
+	if err := r.db.SelectContext(ctx, &revisions, q, entryID); err != nil {
+		return nil, fmt.Errorf("error fetching entry revisions: %s", err)
+	}
+
+	return revisions, nil
+}
+
 func (r Repo) UpdateFeed(ctx context.Context, id string, args seymour.UpdateFeedArgs) error {
 	q := sq.Update("feeds")
 	if args.Title != "" {
@@ -192,6 +508,57 @@ func (r Repo) UpdateFeed(ctx context.Context, id string, args seymour.UpdateFeed
 	if !args.LastSynced.Time.IsZero() {
 		q = q.Set("last_synced_at", args.LastSynced)
 	}
+	if args.ETag != "" {
+		q = q.Set("etag", args.ETag)
+	}
+	if args.LastModified != "" {
+		q = q.Set("last_modified", args.LastModified)
+	}
+	if args.ParseWarning != nil {
+		q = q.Set("parse_warning", sql.NullString{String: *args.ParseWarning, Valid: *args.ParseWarning != ""})
+	}
+	if !args.NextSync.Time.IsZero() {
+		q = q.Set("next_sync_at", seymour.DBTime{Time: args.NextSync.Time.UTC()})
+	}
+	if args.SyncInterval != 0 {
+		q = q.Set("sync_interval", args.SyncInterval)
+	}
+	if args.SkipHours != nil {
+		q = q.Set("skip_hours", sql.NullString{String: *args.SkipHours, Valid: *args.SkipHours != ""})
+	}
+	if args.SkipDays != nil {
+		q = q.Set("skip_days", sql.NullString{String: *args.SkipDays, Valid: *args.SkipDays != ""})
+	}
+	if args.HubURL != nil {
+		q = q.Set("hub_url", sql.NullString{String: *args.HubURL, Valid: *args.HubURL != ""})
+	}
+	if args.TopicURL != nil {
+		q = q.Set("topic_url", sql.NullString{String: *args.TopicURL, Valid: *args.TopicURL != ""})
+	}
+	if args.Status != "" {
+		q = q.Set("status", args.Status)
+	}
+	if args.ConsecutiveFailures != nil {
+		q = q.Set("consecutive_failures", *args.ConsecutiveFailures)
+	}
+	if args.LastError != "" {
+		q = q.Set("last_error", args.LastError)
+	}
+	if !args.LastErrorAt.Time.IsZero() {
+		q = q.Set("last_error_at", args.LastErrorAt)
+	}
+	if !args.LastSuccess.Time.IsZero() {
+		q = q.Set("last_success_at", args.LastSuccess)
+	}
+	if args.Credentials != nil {
+		q = q.Set("credentials", sql.NullString{String: *args.Credentials, Valid: *args.Credentials != ""})
+	}
+	if args.SourceConfig != nil {
+		q = q.Set("source_config", sql.NullString{String: *args.SourceConfig, Valid: *args.SourceConfig != ""})
+	}
+	if args.SourceCursor != nil {
+		q = q.Set("source_cursor", sql.NullString{String: *args.SourceCursor, Valid: *args.SourceCursor != ""})
+	}
 	q = q.Where(sq.Eq{"id": id})
 
 	query, qArgs, err := q.ToSql()
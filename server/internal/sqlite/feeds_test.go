@@ -0,0 +1,269 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/jdholdren/seymour/internal/migrations"
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// testRepo is a migrated database of its own, with a feed to put entries in.
+func testRepo(t *testing.T) (Repo, string) {
+	t.Helper()
+
+	dbx, err := sqlx.Open("sqlite", "file:"+t.TempDir()+"/seymour.sqlite")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = dbx.Close() })
+	require.NoError(t, migrations.Run(dbx))
+
+	repo := New(dbx)
+	feed, err := repo.InsertFeed(context.Background(), "https://example.com/feed.xml", seymour.FeedKindFeed)
+	require.NoError(t, err)
+
+	return repo, feed.ID
+}
+
+func TestInsertEntries_Changes(t *testing.T) {
+	ctx := context.Background()
+	entry := func(feedID, title string) seymour.FeedEntry {
+		return seymour.FeedEntry{
+			FeedID:      feedID,
+			GUID:        "post-1",
+			Title:       title,
+			Description: "About the post",
+			Link:        "https://example.com/post-1",
+			PublishTime: seymour.DBTime{Time: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		}
+	}
+	// insert syncs the entry, returning it as stored
+	insert := func(t *testing.T, repo Repo, e seymour.FeedEntry) seymour.FeedEntry {
+		t.Helper()
+
+		entries := []seymour.FeedEntry{e}
+		require.NoError(t, repo.InsertEntries(ctx, entries))
+		stored, err := repo.Entry(ctx, entries[0].ID)
+		require.NoError(t, err)
+		return stored
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		repo, feedID := testRepo(t)
+		first := insert(t, repo, entry(feedID, "Hello"))
+		again := insert(t, repo, entry(feedID, "Hello"))
+
+		assert.Equal(t, first.ID, again.ID)
+		assert.Nil(t, again.UpdatedAt)
+		revisions, err := repo.EntryRevisions(ctx, first.ID)
+		require.NoError(t, err)
+		assert.Empty(t, revisions)
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		repo, feedID := testRepo(t)
+		first := insert(t, repo, entry(feedID, "Hello"))
+		changed := insert(t, repo, entry(feedID, "Hello, corrected"))
+
+		assert.Equal(t, first.ID, changed.ID)
+		assert.Equal(t, "Hello, corrected", changed.Title)
+		assert.NotEqual(t, first.ContentHash, changed.ContentHash)
+		assert.NotNil(t, changed.UpdatedAt)
+
+		revisions, err := repo.EntryRevisions(ctx, first.ID)
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		assert.Equal(t, "Hello", revisions[0].Title)
+		assert.Equal(t, first.ContentHash, revisions[0].ContentHash)
+	})
+
+	t.Run("changed details", func(t *testing.T) {
+		repo, feedID := testRepo(t)
+		episode := entry(feedID, "Episode 1")
+		episode.Media = []seymour.EntryMedia{{URL: "https://example.com/ep1-wrong.mp3", MIMEType: "audio/mpeg"}}
+		episode.Authors = []string{"Jane"}
+		episode.Categories = []string{"podcast"}
+		first := insert(t, repo, episode)
+
+		// Corrected along with the title
+		episode.Title = "Episode 1, corrected"
+		episode.Media = []seymour.EntryMedia{{URL: "https://example.com/ep1.mp3", MIMEType: "audio/mpeg"}}
+		episode.Authors = []string{"Jane Doe"}
+		episode.Categories = nil
+		insert(t, repo, episode)
+
+		stored, err := repo.Entries(ctx, []string{first.ID})
+		require.NoError(t, err)
+		require.Len(t, stored, 1)
+		require.Len(t, stored[0].Media, 1)
+		assert.Equal(t, "https://example.com/ep1.mp3", stored[0].Media[0].URL)
+		assert.Equal(t, []string{"Jane Doe"}, stored[0].Authors)
+		assert.Empty(t, stored[0].Categories)
+	})
+
+	t.Run("flagged again", func(t *testing.T) {
+		repo, feedID := testRepo(t)
+		first := insert(t, repo, entry(feedID, "Hello"))
+		insert(t, repo, entry(feedID, "Hello, corrected"))
+
+		// Flagged a while ago, long enough that a new flag is told apart from it
+		before := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+		_, err := repo.db.ExecContext(ctx, `UPDATE feed_entries SET updated_at = ? WHERE id = ?;`, seymour.DBTime{Time: before}, first.ID)
+		require.NoError(t, err)
+
+		changed := insert(t, repo, entry(feedID, "Hello, corrected again"))
+		require.NotNil(t, changed.UpdatedAt)
+		assert.True(t, changed.UpdatedAt.Time.After(before))
+
+		revisions, err := repo.EntryRevisions(ctx, first.ID)
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		titles := []string{revisions[0].Title, revisions[1].Title}
+		assert.ElementsMatch(t, []string{"Hello", "Hello, corrected"}, titles)
+	})
+}
+
+func TestSetEntrySummary(t *testing.T) {
+	ctx := context.Background()
+	repo, feedID := testRepo(t)
+
+	// Like a sitemap's page, titled with its url until it's read
+	page := seymour.FeedEntry{FeedID: feedID, GUID: "https://example.com/docs", Title: "https://example.com/docs", Link: "https://example.com/docs"}
+	entries := []seymour.FeedEntry{page}
+	require.NoError(t, repo.InsertEntries(ctx, entries))
+	id := entries[0].ID
+
+	require.NoError(t, repo.SetEntrySummary(ctx, id, "The docs", "All about it."))
+
+	// Synced again as it was, which isn't a change to the entry
+	require.NoError(t, repo.InsertEntries(ctx, []seymour.FeedEntry{page}))
+	stored, err := repo.Entry(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "The docs", stored.Title)
+	assert.Equal(t, "All about it.", stored.Description)
+	assert.Nil(t, stored.UpdatedAt)
+	revisions, err := repo.EntryRevisions(ctx, id)
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+}
+
+func TestMoveFeed(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("move", func(t *testing.T) {
+		repo, feedID := testRepo(t)
+		require.NoError(t, repo.InsertEntries(ctx, []seymour.FeedEntry{{FeedID: feedID, GUID: "a", Title: "A"}}))
+
+		movedID, err := repo.MoveFeed(ctx, feedID, "https://example.com/moved.xml")
+		require.NoError(t, err)
+		assert.Equal(t, feedID, movedID)
+
+		feed, err := repo.Feed(ctx, feedID)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/moved.xml", feed.URL)
+		// Still found by where it used to be
+		old, err := repo.FeedByURL(ctx, "https://example.com/feed.xml")
+		require.NoError(t, err)
+		assert.Equal(t, feedID, old.ID)
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		repo, feedID := testRepo(t)
+		target, err := repo.InsertFeed(ctx, "https://example.com/moved.xml", seymour.FeedKindFeed)
+		require.NoError(t, err)
+
+		// The moving feed is private, the one already at the url was subscribed to without credentials
+		credentials := "sealed"
+		require.NoError(t, repo.UpdateFeed(ctx, feedID, seymour.UpdateFeedArgs{Credentials: &credentials}))
+		require.NoError(t, repo.CreateSubscription(ctx, feedID))
+
+		// Both have b
+		moving := []seymour.FeedEntry{{FeedID: feedID, GUID: "a", Title: "A"}, {FeedID: feedID, GUID: "b", Title: "B"}}
+		require.NoError(t, repo.InsertEntries(ctx, moving))
+		require.NoError(t, repo.InsertEntries(ctx, []seymour.FeedEntry{
+			{FeedID: target.ID, GUID: "b", Title: "B"},
+			{FeedID: target.ID, GUID: "c", Title: "C"},
+		}))
+		for _, e := range moving {
+			require.NoError(t, repo.InsertEntry(ctx, seymour.TimelineEntry{FeedEntryID: e.ID, FeedID: feedID, Status: seymour.TimelineEntryStatusApproved}))
+		}
+
+		movedID, err := repo.MoveFeed(ctx, feedID, target.URL)
+		require.NoError(t, err)
+		assert.Equal(t, target.ID, movedID)
+
+		_, err = repo.Feed(ctx, feedID)
+		assert.ErrorIs(t, err, seymour.ErrNotFound)
+		merged, err := repo.Feed(ctx, target.ID)
+		require.NoError(t, err)
+		require.NotNil(t, merged.Credentials)
+		assert.Equal(t, "sealed", *merged.Credentials)
+		old, err := repo.FeedByURL(ctx, "https://example.com/feed.xml")
+		require.NoError(t, err)
+		assert.Equal(t, target.ID, old.ID)
+
+		var guids []string
+		require.NoError(t, repo.db.SelectContext(ctx, &guids, `SELECT guid FROM feed_entries WHERE feed_id = ? ORDER BY guid;`, target.ID))
+		assert.Equal(t, []string{"a", "b", "c"}, guids)
+
+		// Only the timeline entry for the one it didn't have comes along
+		var timeline []string
+		require.NoError(t, repo.db.SelectContext(ctx, &timeline, `SELECT feed_entry_id FROM timeline_entries WHERE feed_id = ?;`, target.ID))
+		assert.Equal(t, []string{moving[0].ID}, timeline)
+
+		sub, err := repo.Subscription(ctx, target.ID)
+		require.NoError(t, err)
+		assert.Equal(t, target.ID, sub.FeedID)
+	})
+}
+
+func TestDueFeedIDs(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := testRepo(t)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	feed := func(url string, kind seymour.FeedKind, args seymour.UpdateFeedArgs) string {
+		t.Helper()
+
+		f, err := repo.InsertFeed(ctx, url, kind)
+		require.NoError(t, err)
+		require.NoError(t, repo.UpdateFeed(ctx, f.ID, args))
+		return f.ID
+	}
+	var (
+		due        = feed("https://example.com/due.xml", seymour.FeedKindFeed, seymour.UpdateFeedArgs{NextSync: seymour.DBTime{Time: now.Add(-time.Minute)}})
+		alsoDue    = feed("https://example.com/also-due.xml", seymour.FeedKindFeed, seymour.UpdateFeedArgs{NextSync: seymour.DBTime{Time: now}})
+		notYet     = feed("https://example.com/not-yet.xml", seymour.FeedKindFeed, seymour.UpdateFeedArgs{NextSync: seymour.DBTime{Time: now.Add(time.Minute)}})
+		broken     = feed("https://example.com/broken.xml", seymour.FeedKindFeed, seymour.UpdateFeedArgs{Status: seymour.FeedStatusBroken})
+		newsletter = feed("newsletter:abc", seymour.FeedKindNewsletter, seymour.UpdateFeedArgs{NextSync: seymour.DBTime{Time: now.Add(-time.Minute)}})
+	)
+
+	ids, err := repo.DueFeedIDs(ctx, now, "", 100)
+	require.NoError(t, err)
+	// Along with the feed testRepo makes, which has never synced
+	assert.Len(t, ids, 3)
+	assert.Contains(t, ids, due)
+	assert.Contains(t, ids, alsoDue)
+	assert.NotContains(t, ids, notYet)
+	assert.NotContains(t, ids, broken)
+	assert.NotContains(t, ids, newsletter)
+
+	// Paged through in id order, each page after the last id of the one before
+	var paged []string
+	for after := ""; ; {
+		page, err := repo.DueFeedIDs(ctx, now, after, 2)
+		require.NoError(t, err)
+		if len(page) == 0 {
+			break
+		}
+		assert.LessOrEqual(t, len(page), 2)
+		paged = append(paged, page...)
+		after = page[len(page)-1]
+	}
+	assert.Equal(t, ids, paged)
+}
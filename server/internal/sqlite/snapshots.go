@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+const snapshotNamespace = "-snap"
+
+func (r Repo) Snapshot(ctx context.Context, id string) (seymour.PageSnapshot, error) {
+	const q = `SELECT * FROM page_snapshots WHERE id = ?;`
+
+	return r.getSnapshot(ctx, q, id)
+}
+
+// PreviousSnapshot returns the snapshot of the same page that came before the one with the ID.
+func (r Repo) PreviousSnapshot(ctx context.Context, id string) (seymour.PageSnapshot, error) {
+	const q = `SELECT * FROM page_snapshots
+	WHERE feed_id = (SELECT feed_id FROM page_snapshots WHERE id = ?)
+		AND rowid < (SELECT rowid FROM page_snapshots WHERE id = ?)
+	ORDER BY rowid DESC
+	LIMIT 1;`
+
+	return r.getSnapshot(ctx, q, id, id)
+}
+
+// LatestSnapshot returns the feed's most recent snapshot.
+func (r Repo) LatestSnapshot(ctx context.Context, feedID string) (seymour.PageSnapshot, error) {
+	const q = `SELECT * FROM page_snapshots WHERE feed_id = ? ORDER BY rowid DESC LIMIT 1;`
+
+	return r.getSnapshot(ctx, q, feedID)
+}
+
+func (r Repo) getSnapshot(ctx context.Context, q string, args ...any) (seymour.PageSnapshot, error) {
+	var snapshot seymour.PageSnapshot
+	err := r.db.GetContext(ctx, &snapshot, q, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return seymour.PageSnapshot{}, seymour.ErrNotFound
+	}
+	if err != nil {
+		return seymour.PageSnapshot{}, fmt.Errorf("error fetching snapshot: %s", err)
+	}
+
+	return snapshot, nil
+}
+
+// InsertSnapshot stores a new snapshot of the page, returning it with its ID.
+func (r Repo) InsertSnapshot(ctx context.Context, snapshot seymour.PageSnapshot) (seymour.PageSnapshot, error) {
+	const q = `INSERT INTO page_snapshots (id, feed_id, title, content, content_hash)
+	VALUES (:id, :feed_id, :title, :content, :content_hash);`
+
+	snapshot.ID = fmt.Sprintf("%s%s", uuid.NewString(), snapshotNamespace)
+	if _, err := r.db.NamedExecContext(ctx, q, snapshot); err != nil {
+		return seymour.PageSnapshot{}, fmt.Errorf("error inserting snapshot: %s", err)
+	}
+
+	return r.Snapshot(ctx, snapshot.ID)
+}
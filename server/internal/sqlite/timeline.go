@@ -138,6 +138,7 @@ func (r Repo) TimelineEntries(ctx context.Context, args seymour.TimelineEntriesA
 	if args.FeedID != "" {
 		q = q.Where("feed_id = ?", args.FeedID)
 	}
+	q = whereEntryDetails(q, args)
 
 	if len(where) > 0 {
 		q = q.Where(where)
@@ -165,6 +166,7 @@ func (r Repo) CountTimelineEntries(ctx context.Context, args seymour.TimelineEnt
 	if args.FeedID != "" {
 		q = q.Where("feed_id = ?", args.FeedID)
 	}
+	q = whereEntryDetails(q, args)
 
 	if len(where) > 0 {
 		q = q.Where(where)
@@ -182,3 +184,19 @@ func (r Repo) CountTimelineEntries(ctx context.Context, args seymour.TimelineEnt
 
 	return count, nil
 }
+
+// whereEntryDetails narrows the timeline entries down by the author or category of their feed entry,
+// and leaves out backfilled entries unless they're asked for.
+func whereEntryDetails(q sq.SelectBuilder, args seymour.TimelineEntriesArgs) sq.SelectBuilder {
+	if !args.IncludeBackfilled {
+		q = q.Where("feed_entry_id NOT IN (SELECT id FROM feed_entries WHERE backfilled = 1)")
+	}
+	if args.Author != "" {
+		q = q.Where("feed_entry_id IN (SELECT feed_entry_id FROM entry_authors WHERE name = ? COLLATE NOCASE)", args.Author)
+	}
+	if args.Category != "" {
+		q = q.Where("feed_entry_id IN (SELECT feed_entry_id FROM entry_categories WHERE name = ? COLLATE NOCASE)", args.Category)
+	}
+
+	return q
+}
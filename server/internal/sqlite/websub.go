@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+func (r Repo) WebSubSubscription(ctx context.Context, feedID string) (seymour.WebSubSubscription, error) {
+	const q = `SELECT * FROM websub_subscriptions WHERE feed_id = ?;`
+
+	var sub seymour.WebSubSubscription
+	err := r.db.GetContext(ctx, &sub, q, feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return seymour.WebSubSubscription{}, seymour.ErrNotFound
+	}
+	if err != nil {
+		return seymour.WebSubSubscription{}, fmt.Errorf("error fetching websub subscription: %s", err)
+	}
+
+	return sub, nil
+}
+
+// UpsertWebSubSubscription creates or replaces the feed's subscription, stamping when it was updated.
+func (r Repo) UpsertWebSubSubscription(ctx context.Context, sub seymour.WebSubSubscription) error {
+	const q = `INSERT INTO websub_subscriptions (feed_id, hub_url, topic_url, secret, state, lease_expires_at, updated_at)
+	VALUES (:feed_id, :hub_url, :topic_url, :secret, :state, :lease_expires_at, :updated_at)
+	ON CONFLICT(feed_id) DO UPDATE SET
+		hub_url = excluded.hub_url,
+		topic_url = excluded.topic_url,
+		secret = excluded.secret,
+		state = excluded.state,
+		lease_expires_at = excluded.lease_expires_at,
+		updated_at = excluded.updated_at;`
+
+	sub.UpdatedAt = seymour.DBTime{Time: time.Now().UTC()}
+	if sub.LeaseExpiresAt != nil {
+		sub.LeaseExpiresAt = &seymour.DBTime{Time: sub.LeaseExpiresAt.Time.UTC()}
+	}
+	if _, err := r.db.NamedExecContext(ctx, q, sub); err != nil {
+		return fmt.Errorf("error upserting websub subscription: %s", err)
+	}
+
+	return nil
+}
+
+// FeedsNeedingWebSub returns the IDs of feeds with a hub that need to subscribe to it.
+//
+// That's feeds that haven't subscribed yet or whose hub changed, leases expiring before renewBefore,
+// and requests the hub still hasn't verified by retryBefore. Broken feeds are left alone.
+func (r Repo) FeedsNeedingWebSub(ctx context.Context, renewBefore, retryBefore time.Time) ([]string, error) {
+	const q = `SELECT feeds.id FROM feeds
+	LEFT JOIN websub_subscriptions ws ON ws.feed_id = feeds.id
+	WHERE feeds.hub_url IS NOT NULL AND feeds.hub_url != '' AND feeds.status != 'broken' AND (
+		ws.feed_id IS NULL
+		OR ws.hub_url != feeds.hub_url
+		OR ws.topic_url != feeds.topic_url
+		OR (ws.state = 'verified' AND ws.lease_expires_at <= ?)
+		OR (ws.state = 'pending' AND ws.updated_at <= ?)
+	);`
+
+	var ids []string
+	if err := r.db.SelectContext(ctx, &ids, q, seymour.DBTime{Time: renewBefore.UTC()}, seymour.DBTime{Time: retryBefore.UTC()}); err != nil {
+		return nil, fmt.Errorf("error fetching feeds needing websub: %s", err)
+	}
+
+	return ids, nil
+}
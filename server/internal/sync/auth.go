@@ -0,0 +1,101 @@
+package sync
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// WithAuth returns a copy of the fetcher that sends the credentials along with its requests.
+//
+// They only go to the host the request was made to, not wherever it's redirected to.
+func (f *Fetcher) WithAuth(creds seymour.FeedCredentials) *Fetcher {
+	withAuth := *f
+	withAuth.creds = creds
+	withAuth.client = &http.Client{
+		Transport:     f.client.Transport,
+		CheckRedirect: credentialRedirects(creds),
+	}
+
+	return &withAuth
+}
+
+// applyCredentials adds the credentials to the request.
+func applyCredentials(req *http.Request, creds seymour.FeedCredentials) {
+	if creds.Username != "" || creds.Password != "" {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+	if creds.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+creds.Token)
+	}
+	for name, value := range creds.Headers {
+		req.Header.Set(name, value)
+	}
+	if len(creds.Query) > 0 {
+		query := req.URL.Query()
+		for name, value := range creds.Query {
+			query.Set(name, value)
+		}
+		req.URL.RawQuery = query.Encode()
+	}
+}
+
+// withoutCredentials takes the credentials' query parameters back off a url the request ended up
+// at, e.g. after a redirect, so it can be stored and shown without them.
+func withoutCredentials(rawURL string, creds seymour.FeedCredentials) string {
+	if rawURL == "" || len(creds.Query) == 0 {
+		return rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// Better to not follow the move than to keep a secret in it
+		return ""
+	}
+	query := u.Query()
+	for name := range creds.Query {
+		query.Del(name)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// credentialRedirects keeps the credentials' headers from following a redirect to another host.
+//
+// Go already drops the Authorization header in that case, but not any others.
+func credentialRedirects(creds seymour.FeedCredentials) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		// Same as the default policy
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+
+		if req.URL.Host != via[0].URL.Host {
+			req.Header.Del("Authorization")
+			for name := range creds.Headers {
+				req.Header.Del(name)
+			}
+		}
+
+		return nil
+	}
+}
+
+// redactURLError takes the query off the url in a request error, since it can hold credentials
+// that would otherwise end up in the feed's last error.
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+
+	if u, parseErr := url.Parse(urlErr.URL); parseErr == nil && u.RawQuery != "" {
+		u.RawQuery = "redacted"
+		urlErr.URL = u.String()
+	}
+
+	return err
+}
@@ -0,0 +1,100 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+func TestFetcher_WithAuth(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(testRSSFeed))
+	}))
+	defer srv.Close()
+
+	t.Run("basic auth", func(t *testing.T) {
+		f := testFetcher.WithAuth(seymour.FeedCredentials{Username: "me", Password: "hunter2"})
+		_, _, err := f.Feed(context.Background(), seymour.Feed{ID: "feed-private", URL: srv.URL})
+		require.NoError(t, err)
+
+		user, pass, ok := got.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "me", user)
+		assert.Equal(t, "hunter2", pass)
+	})
+
+	t.Run("token, headers, and query", func(t *testing.T) {
+		f := testFetcher.WithAuth(seymour.FeedCredentials{
+			Token:   "tok",
+			Headers: map[string]string{"X-Api-Key": "key"},
+			Query:   map[string]string{"token": "secret"},
+		})
+		_, _, err := f.Feed(context.Background(), seymour.Feed{ID: "feed-private", URL: srv.URL + "/feed?page=1"})
+		require.NoError(t, err)
+
+		assert.Equal(t, "Bearer tok", got.Header.Get("Authorization"))
+		assert.Equal(t, "key", got.Header.Get("X-Api-Key"))
+		assert.Equal(t, "secret", got.URL.Query().Get("token"))
+		assert.Equal(t, "1", got.URL.Query().Get("page"))
+	})
+
+	t.Run("not sent by the plain fetcher", func(t *testing.T) {
+		_, _, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-public", URL: srv.URL})
+		require.NoError(t, err)
+
+		assert.Empty(t, got.Header.Get("Authorization"))
+		assert.Empty(t, got.Header.Get("X-Api-Key"))
+	})
+
+	t.Run("not sent to another host", func(t *testing.T) {
+		// Another host as far as the client's concerned, even though it's the same server
+		port := srv.Listener.Addr().(*net.TCPAddr).Port
+		redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, fmt.Sprintf("http://localhost:%d/feed", port), http.StatusFound)
+		}))
+		defer redirector.Close()
+
+		f := testFetcher.WithAuth(seymour.FeedCredentials{Token: "tok", Headers: map[string]string{"X-Api-Key": "key"}})
+		_, _, err := f.Feed(context.Background(), seymour.Feed{ID: "feed-private", URL: redirector.URL})
+		require.NoError(t, err)
+
+		assert.Empty(t, got.Header.Get("Authorization"))
+		assert.Empty(t, got.Header.Get("X-Api-Key"))
+	})
+
+	t.Run("query kept out of a moved url", func(t *testing.T) {
+		mover := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/feed" {
+				// Moves that keep the query along, like most http to https ones do
+				http.Redirect(w, r, "/moved?"+r.URL.RawQuery, http.StatusMovedPermanently)
+				return
+			}
+			_, _ = w.Write([]byte(testRSSFeed))
+		}))
+		defer mover.Close()
+
+		f := testFetcher.WithAuth(seymour.FeedCredentials{Query: map[string]string{"token": "secret"}})
+		feed, _, err := f.Feed(context.Background(), seymour.Feed{ID: "feed-private", URL: mover.URL + "/feed?page=1"})
+		require.NoError(t, err)
+
+		assert.Equal(t, mover.URL+"/moved?page=1", feed.URL)
+	})
+
+	t.Run("query kept out of errors", func(t *testing.T) {
+		f := testFetcher.WithAuth(seymour.FeedCredentials{Query: map[string]string{"token": "secret"}})
+		_, _, err := f.Feed(context.Background(), seymour.Feed{ID: "feed-private", URL: "http://127.0.0.1:1/feed"})
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "secret")
+	})
+}
@@ -0,0 +1,109 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// paging collects where a feed document says its older entries are.
+type paging struct {
+	next      string // RFC 5005: a paged feed's "next", or an archived feed's "prev-archive"
+	wordPress bool   // WordPress pages its feeds with ?paged=N without saying so
+}
+
+// pagingLinks picks the link to the older entries out of a feed's links.
+//
+// An archive's "prev-archive" wins over a paged feed's "next", since archive documents
+// don't change once they're written.
+func pagingLinks(links []feedLink, pages *paging) {
+	var next, prevArchive string
+	for _, l := range links {
+		switch {
+		case next == "" && l.Rel == "next":
+			next = strings.TrimSpace(l.Href)
+		case prevArchive == "" && l.Rel == "prev-archive":
+			prevArchive = strings.TrimSpace(l.Href)
+		}
+	}
+
+	pages.next = prevArchive
+	if pages.next == "" {
+		pages.next = next
+	}
+}
+
+// isWordPress checks a feed's generator for WordPress.
+func isWordPress(generator string) bool {
+	generator = strings.ToLower(generator)
+	return strings.Contains(generator, "wordpress.org") || strings.HasPrefix(strings.TrimSpace(generator), "wordpress")
+}
+
+// FeedPage is a page of a feed's older entries, see [Fetcher.Page].
+type FeedPage struct {
+	Entries []seymour.FeedEntry
+	Next    string // Where the entries before these are, empty if there aren't any more
+}
+
+// Page fetches a page of the feed's history, starting from the feed's own url.
+//
+// Older pages are found by the feed's RFC 5005 links, or for WordPress feeds by guessing the
+// next ?paged=N. A page that isn't there is the end of the history rather than an error.
+func (f *Fetcher) Page(ctx context.Context, feedID, pageURL string) (FeedPage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return FeedPage{}, fmt.Errorf("error creating page request: %w", err)
+	}
+
+	resp, body, err := f.do(req)
+	if err != nil {
+		return FeedPage{}, fmt.Errorf("error getting page url: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return FeedPage{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return FeedPage{}, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	pages := &paging{}
+	_, entries, err := parse(feedID, resp.Header.Get("Content-Type"), body, &schedule{}, pages)
+	if err != nil {
+		return FeedPage{}, err
+	}
+	page := FeedPage{Entries: entries}
+
+	// Links are relative to where the page ended up, after any redirects
+	base := resp.Request.URL
+	switch {
+	case pages.next != "":
+		if next, err := base.Parse(pages.next); err == nil {
+			page.Next = next.String()
+		}
+	case pages.wordPress && len(entries) > 0:
+		page.Next = nextWordPressPage(base)
+	}
+	// The credentials were added to this request's query, and are added again when the next page is fetched
+	page.Next = withoutCredentials(page.Next, f.creds)
+
+	return page, nil
+}
+
+// nextWordPressPage works out the url of the WordPress feed page after this one.
+func nextWordPressPage(u *url.URL) string {
+	query := u.Query()
+	current, err := strconv.Atoi(query.Get("paged"))
+	if err != nil || current < 1 {
+		current = 1
+	}
+	query.Set("paged", strconv.Itoa(current+1))
+
+	next := *u
+	next.RawQuery = query.Encode()
+	return next.String()
+}
@@ -0,0 +1,146 @@
+package sync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+func TestFetcher_Page(t *testing.T) {
+	t.Run("atom archives", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/atom+xml")
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Current</title>
+  <link rel="next" href="/feed?page=2"/>
+  <link rel="prev-archive" href="archive/2025"/>
+  <entry><id>urn:new</id><title>New</title><link href="https://example.com/new"/><updated>2026-01-01T00:00:00Z</updated></entry>
+</feed>`))
+		})
+		mux.HandleFunc("/archive/2025", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/atom+xml")
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>2025</title>
+  <entry><id>urn:old</id><title>Old</title><link href="https://example.com/old"/><updated>2025-01-01T00:00:00Z</updated></entry>
+</feed>`))
+		})
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+
+		page, err := testFetcher.Page(context.Background(), "feed-1", srv.URL+"/feed")
+		require.NoError(t, err)
+		require.Len(t, page.Entries, 1)
+		assert.Equal(t, "urn:new", page.Entries[0].GUID)
+		// The archive wins over the next page, relative to the page
+		assert.Equal(t, srv.URL+"/archive/2025", page.Next)
+
+		page, err = testFetcher.Page(context.Background(), "feed-1", page.Next)
+		require.NoError(t, err)
+		require.Len(t, page.Entries, 1)
+		assert.Equal(t, "urn:old", page.Entries[0].GUID)
+		assert.Empty(t, page.Next)
+	})
+
+	t.Run("rss next", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/rss+xml")
+			_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>Paged</title>
+    <atom:link rel="next" href="https://example.com/feed?page=2"/>
+    <item><guid>a</guid><title>A</title><link>https://example.com/a</link></item>
+  </channel>
+</rss>`))
+		}))
+		defer srv.Close()
+
+		page, err := testFetcher.Page(context.Background(), "feed-1", srv.URL)
+		require.NoError(t, err)
+		assert.Len(t, page.Entries, 1)
+		assert.Equal(t, "https://example.com/feed?page=2", page.Next)
+	})
+
+	t.Run("json feed next_url", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/feed+json")
+			_, _ = w.Write([]byte(`{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Paged",
+  "next_url": "https://example.com/feed.json?page=2",
+  "items": [{"id": "a", "url": "https://example.com/a", "title": "A"}]
+}`))
+		}))
+		defer srv.Close()
+
+		page, err := testFetcher.Page(context.Background(), "feed-1", srv.URL)
+		require.NoError(t, err)
+		assert.Len(t, page.Entries, 1)
+		assert.Equal(t, "https://example.com/feed.json?page=2", page.Next)
+	})
+
+	t.Run("wordpress", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("paged") == "3" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/rss+xml")
+			_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Blog</title>
+    <generator>https://wordpress.org/?v=6.5</generator>
+    <item><guid>a</guid><title>A</title><link>https://example.com/a</link></item>
+  </channel>
+</rss>`))
+		}))
+		defer srv.Close()
+
+		page, err := testFetcher.Page(context.Background(), "feed-1", srv.URL+"/feed/")
+		require.NoError(t, err)
+		assert.Equal(t, srv.URL+"/feed/?paged=2", page.Next)
+
+		page, err = testFetcher.Page(context.Background(), "feed-1", page.Next)
+		require.NoError(t, err)
+		assert.Equal(t, srv.URL+"/feed/?paged=3", page.Next)
+
+		// WordPress 404s past the last page, which is just the end
+		page, err = testFetcher.Page(context.Background(), "feed-1", page.Next)
+		require.NoError(t, err)
+		assert.Empty(t, page.Entries)
+		assert.Empty(t, page.Next)
+	})
+
+	t.Run("query credentials kept out of next", func(t *testing.T) {
+		var gotToken string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotToken = r.URL.Query().Get("token")
+			w.Header().Set("Content-Type", "application/rss+xml")
+			_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Blog</title>
+    <generator>https://wordpress.org/?v=6.5</generator>
+    <item><guid>a</guid><title>A</title><link>https://example.com/a</link></item>
+  </channel>
+</rss>`))
+		}))
+		defer srv.Close()
+
+		f := testFetcher.WithAuth(seymour.FeedCredentials{Query: map[string]string{"token": "secret"}})
+		page, err := f.Page(context.Background(), "feed-1", srv.URL+"/feed/")
+		require.NoError(t, err)
+		assert.Equal(t, "secret", gotToken)
+		assert.Equal(t, srv.URL+"/feed/?paged=2", page.Next)
+	})
+}
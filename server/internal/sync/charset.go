@@ -0,0 +1,85 @@
+package sync
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Matches the encoding declared in an XML prolog, e.g. <?xml version="1.0" encoding="ISO-8859-1"?>
+var xmlEncodingRe = regexp.MustCompile(`^<\?xml[^>]*\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+)
+
+// toUTF8 transcodes a feed document to UTF-8 so the parsers only ever have to deal with that.
+//
+// A byte order mark wins, then the charset from the Content-Type header, then the encoding
+// declared in the XML prolog, as RFC 7303 section 3.2 has it. Without any of them the document
+// is assumed to be UTF-8.
+func toUTF8(contentType string, body []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(body, utf8BOM):
+		return body[len(utf8BOM):], nil
+	case bytes.HasPrefix(body, utf16BEBOM):
+		return decodeWith(unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM), body)
+	case bytes.HasPrefix(body, utf16LEBOM):
+		return decodeWith(unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), body)
+	}
+
+	// A server that transcodes documents leaves their prolog as it was, so its charset comes first
+	var label string
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		label = params["charset"]
+	}
+	if label == "" {
+		if m := xmlEncodingRe.FindSubmatch(bytes.TrimLeft(body, " \t\r\n")); m != nil {
+			label = string(m[1])
+		}
+	}
+	if label == "" {
+		return body, nil
+	}
+
+	enc, name := charset.Lookup(label)
+	if enc == nil {
+		return nil, fmt.Errorf("unsupported charset: %s", label)
+	}
+	if name == "utf-8" {
+		return body, nil
+	}
+
+	return decodeWith(enc, body)
+}
+
+func decodeWith(enc encoding.Encoding, body []byte) ([]byte, error) {
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("error transcoding feed to utf-8: %w", err)
+	}
+
+	return decoded, nil
+}
+
+// newXMLDecoder creates a decoder for a document that's already been through [toUTF8].
+//
+// The prolog may still declare the original encoding, so it's accepted as is instead
+// of the decoder refusing anything but UTF-8.
+func newXMLDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	return decoder
+}
@@ -0,0 +1,156 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/araddon/dateparse"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// Layouts tried before handing off to dateparse: the ones from the specs, then
+// the variations of them that show up in real feeds.
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	time.RFC3339Nano,
+	time.RFC822Z,
+	time.RFC822,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 02 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"02 Jan 2006 15:04:05 -0700",
+	"Mon, 2 January 2006 15:04:05 -0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Offsets for the timezone abbreviations feeds commonly use.
+//
+// Go parses unknown abbreviations as a zero offset, so they're swapped for numeric offsets before parsing.
+var zoneOffsets = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"AKST": "-0900",
+	"AKDT": "-0800",
+	"HST":  "-1000",
+	"WET":  "+0000",
+	"WEST": "+0100",
+	"BST":  "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"MET":  "+0100",
+	"MEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+	"MSK":  "+0300",
+	"IST":  "+0530",
+	"SGT":  "+0800",
+	"HKT":  "+0800",
+	"AWST": "+0800",
+	"JST":  "+0900",
+	"KST":  "+0900",
+	"ACST": "+0930",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+	"NZST": "+1200",
+	"NZDT": "+1300",
+}
+
+// The most unparseable dates to mention in a feed's warning.
+const maxWarnedDates = 3
+
+// dateParser normalizes the publish dates of a feed's entries.
+//
+// Any dates that couldn't be made sense of are collected for the feed's warning.
+type dateParser struct {
+	unparsed []string
+}
+
+// parse returns the first of the candidates that can be parsed, so they should
+// be given in order of preference, e.g. published before updated.
+//
+// Returns the zero time if none of them can be parsed.
+func (d *dateParser) parse(candidates ...string) seymour.DBTime {
+	var unparsed string
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" {
+			continue
+		}
+
+		if t, ok := parseDate(candidate); ok {
+			return seymour.DBTime{Time: t}
+		}
+		if unparsed == "" {
+			unparsed = candidate
+		}
+	}
+
+	if unparsed != "" {
+		d.unparsed = append(d.unparsed, unparsed)
+	}
+
+	return seymour.DBTime{}
+}
+
+// warning describes the dates that couldn't be parsed, or nil if there weren't any.
+func (d *dateParser) warning() *string {
+	if len(d.unparsed) == 0 {
+		return nil
+	}
+
+	examples := d.unparsed[:min(len(d.unparsed), maxWarnedDates)]
+	w := fmt.Sprintf("unable to parse %d publish date(s), e.g. %q", len(d.unparsed), examples)
+	return &w
+}
+
+// parseDate tries the known layouts, then falls back to guessing the format.
+//
+// Dates without a timezone are assumed to be UTC.
+func parseDate(s string) (time.Time, bool) {
+	s = normalizeZone(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+
+	t, err := dateparse.ParseIn(s, time.UTC)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+// normalizeZone replaces a trailing timezone abbreviation with its numeric offset.
+func normalizeZone(s string) string {
+	idx := strings.LastIndex(s, " ")
+	if idx < 0 {
+		return s
+	}
+
+	abbrev := strings.Trim(s[idx+1:], "()")
+	if offset, ok := zoneOffsets[strings.ToUpper(abbrev)]; ok {
+		return s[:idx+1] + offset
+	}
+
+	return s
+}
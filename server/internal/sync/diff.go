@@ -0,0 +1,189 @@
+package sync
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// DiffOp is what happened to a line from one snapshot of a page to the next.
+type DiffOp string
+
+const (
+	DiffSame    DiffOp = "same"
+	DiffAdded   DiffOp = "added"
+	DiffRemoved DiffOp = "removed"
+)
+
+// DiffLine is a line of a [Diff].
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// Past this many line comparisons, a changed stretch is shown as all removed and then all added
+// instead of working out what it has in common.
+const maxDiffCells = 4 << 20
+
+// Diff compares two snapshots' text line by line, keeping every line of both in order. Within a
+// changed stretch, removed lines come before added ones.
+func Diff(before, after string) []DiffLine {
+	var (
+		a = splitLines(before)
+		b = splitLines(after)
+	)
+
+	// What's the same at either end doesn't need comparing
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		lines = append(lines, DiffLine{Op: DiffSame, Text: text})
+	}
+	lines = append(lines, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{Op: DiffSame, Text: text})
+	}
+
+	return lines
+}
+
+// diffMiddle diffs the lines by their longest common subsequence.
+func diffMiddle(a, b []string) []DiffLine {
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	if len(a)*len(b) > maxDiffCells {
+		for _, text := range a {
+			lines = append(lines, DiffLine{Op: DiffRemoved, Text: text})
+		}
+		for _, text := range b {
+			lines = append(lines, DiffLine{Op: DiffAdded, Text: text})
+		}
+		return lines
+	}
+
+	// common[i][j] is how many lines a[i:] and b[j:] have in common
+	width := len(b) + 1
+	common := make([]int, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i*width+j] = common[(i+1)*width+j+1] + 1
+			} else {
+				common[i*width+j] = max(common[(i+1)*width+j], common[i*width+j+1])
+			}
+		}
+	}
+
+	var (
+		i, j  int
+		added []DiffLine
+	)
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(append(lines, added...), DiffLine{Op: DiffSame, Text: a[i]})
+			added = added[:0]
+			i, j = i+1, j+1
+		case j < len(b) && (i == len(a) || common[i*width+j+1] >= common[(i+1)*width+j]):
+			added = append(added, DiffLine{Op: DiffAdded, Text: b[j]})
+			j++
+		default:
+			lines = append(lines, DiffLine{Op: DiffRemoved, Text: a[i]})
+			i++
+		}
+	}
+
+	return append(lines, added...)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(text, "\n")
+}
+
+// SummarizeDiff describes the change in a sentence, e.g. for an entry's description.
+func SummarizeDiff(lines []DiffLine) string {
+	var added, removed []string
+	for _, line := range lines {
+		switch line.Op {
+		case DiffAdded:
+			added = append(added, line.Text)
+		case DiffRemoved:
+			removed = append(removed, line.Text)
+		}
+	}
+
+	var counts []string
+	if len(added) > 0 {
+		counts = append(counts, fmt.Sprintf("%d %s added", len(added), plural(len(added), "line")))
+	}
+	if len(removed) > 0 {
+		counts = append(counts, fmt.Sprintf("%d %s removed", len(removed), plural(len(removed), "line")))
+	}
+	if len(counts) == 0 {
+		return "No changes"
+	}
+
+	// The first new line is usually the most telling, otherwise what went away
+	summary := strings.Join(counts, ", ")
+	switch {
+	case len(added) > 0:
+		summary += fmt.Sprintf(": “%s”", truncateRunes(added[0], 200))
+	case len(removed) > 0:
+		summary += fmt.Sprintf(": “%s” was removed", truncateRunes(removed[0], 200))
+	}
+
+	return summary
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+
+	return word + "s"
+}
+
+// DiffHTML renders the changed lines, with a line of what's around them, for an entry's content.
+func DiffHTML(lines []DiffLine) string {
+	changed := func(i int) bool {
+		return i >= 0 && i < len(lines) && lines[i].Op != DiffSame
+	}
+
+	var (
+		b       strings.Builder
+		skipped bool
+	)
+	for i, line := range lines {
+		if line.Op == DiffSame && !changed(i-1) && !changed(i+1) {
+			skipped = true
+			continue
+		}
+		if skipped && b.Len() > 0 {
+			b.WriteString("<p>…</p>")
+		}
+		skipped = false
+
+		text := html.EscapeString(line.Text)
+		switch line.Op {
+		case DiffAdded:
+			fmt.Fprintf(&b, "<p><ins>%s</ins></p>", text)
+		case DiffRemoved:
+			fmt.Fprintf(&b, "<p><del>%s</del></p>", text)
+		default:
+			fmt.Fprintf(&b, "<p>%s</p>", text)
+		}
+	}
+
+	return b.String()
+}
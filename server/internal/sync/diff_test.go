@@ -0,0 +1,47 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := "Plans\nThe Pro plan is $10 a month.\nUnlimited feeds\nEmail support"
+	after := "Plans\nThe Pro plan is $12 a month.\nUnlimited feeds\nEmail support\nPhone support"
+
+	lines := Diff(before, after)
+	assert.Equal(t, []DiffLine{
+		{Op: DiffSame, Text: "Plans"},
+		{Op: DiffRemoved, Text: "The Pro plan is $10 a month."},
+		{Op: DiffAdded, Text: "The Pro plan is $12 a month."},
+		{Op: DiffSame, Text: "Unlimited feeds"},
+		{Op: DiffSame, Text: "Email support"},
+		{Op: DiffAdded, Text: "Phone support"},
+	}, lines)
+
+	assert.Equal(t, "2 lines added, 1 line removed: “The Pro plan is $12 a month.”", SummarizeDiff(lines))
+	assert.Equal(t,
+		"<p>Plans</p><p><del>The Pro plan is $10 a month.</del></p><p><ins>The Pro plan is $12 a month.</ins></p><p>Unlimited feeds</p>"+
+			"<p>Email support</p><p><ins>Phone support</ins></p>",
+		DiffHTML(lines),
+	)
+
+	t.Run("only removals", func(t *testing.T) {
+		lines := Diff("a\nb\nc", "a\nc")
+		assert.Equal(t, "1 line removed: “b” was removed", SummarizeDiff(lines))
+	})
+
+	t.Run("from nothing", func(t *testing.T) {
+		lines := Diff("", "a\nb")
+		assert.Equal(t, []DiffLine{{Op: DiffAdded, Text: "a"}, {Op: DiffAdded, Text: "b"}}, lines)
+	})
+
+	t.Run("unchanged stretches are skipped", func(t *testing.T) {
+		lines := Diff("1\n2\n3\n4\n5\n6\n7", "1\n2\n3\n4\n5\n6\n<7>")
+		assert.Equal(t, "<p>6</p><p><del>7</del></p><p><ins>&lt;7&gt;</ins></p>", DiffHTML(lines))
+
+		lines = Diff("1\n2\n3\n4\n5", "0\n2\n3\n4\n6")
+		assert.Equal(t, "<p><del>1</del></p><p><ins>0</ins></p><p>2</p><p>…</p><p>4</p><p><del>5</del></p><p><ins>6</ins></p>", DiffHTML(lines))
+	})
+}
@@ -0,0 +1,165 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// The link types that advertise a feed in an HTML page's head.
+var feedLinkTypes = []string{
+	"application/rss+xml",
+	"application/atom+xml",
+	"application/feed+json",
+	"application/rdf+xml",
+}
+
+// Paths that commonly host a site's feed, tried in order when a page doesn't advertise one.
+var commonFeedPaths = []string{
+	"/feed",
+	"/rss",
+	"/index.xml",
+	"/feed.xml",
+	"/atom.xml",
+	"/rss.xml",
+	"/feed.json",
+}
+
+// Discover finds the feeds available at the given URL.
+//
+// If the URL is already a feed, it's returned as the only candidate (or where it permanently
+// moved to, if it was redirected). For HTML pages,
+// the alternate links in the page are used, falling back to probing common feed paths
+// on the same host. An empty result means no feed could be found.
+//
+// URLs for one of the fetcher's sources are returned as they are.
+func (f *Fetcher) Discover(ctx context.Context, pageURL string) ([]string, error) {
+	// Sources have nothing to discover, they're fetched as they are
+	if f.Kind(pageURL) != seymour.FeedKindFeed {
+		return []string{pageURL}, nil
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing url: %w", err)
+	}
+
+	page, err := f.get(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Anything other than HTML is assumed to be the feed itself
+	if !isHTML(page.body) {
+		return []string{page.url}, nil
+	}
+
+	if candidates := feedLinks(base, page.body); len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	// Nothing advertised, so try the usual suspects
+	for _, path := range commonFeedPaths {
+		candidate, err := f.get(ctx, base.ResolveReference(&url.URL{Path: path}).String())
+		if err != nil || isHTML(candidate.body) {
+			continue
+		}
+		if _, _, err := parse("", candidate.contentType, candidate.body, &schedule{}, &paging{}); err != nil {
+			continue
+		}
+
+		return []string{candidate.url}, nil
+	}
+
+	return []string{}, nil
+}
+
+// A successful response from [get].
+type fetchedPage struct {
+	url         string // Where the page permanently lives, after any redirects
+	contentType string
+	body        []byte
+}
+
+// get fetches the url, returning the page if the response was successful.
+func (f *Fetcher) get(ctx context.Context, u string) (fetchedPage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fetchedPage{}, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, body, err := f.do(req)
+	if err != nil {
+		return fetchedPage{}, fmt.Errorf("error getting url: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fetchedPage{}, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	page := fetchedPage{
+		url:         u,
+		contentType: resp.Header.Get("Content-Type"),
+		body:        body,
+	}
+	if movedTo := withoutCredentials(permanentURL(resp), f.creds); movedTo != "" {
+		page.url = movedTo
+	}
+
+	return page, nil
+}
+
+// isHTML sniffs the body to see if it's an HTML page rather than a feed.
+//
+// The content type header isn't trusted since plenty of servers label feeds as text/html.
+func isHTML(body []byte) bool {
+	return strings.HasPrefix(http.DetectContentType(body), "text/html")
+}
+
+// feedLinks pulls the alternate feed links out of an HTML document, resolved against base.
+func feedLinks(base *url.URL, body []byte) []string {
+	links := []string{}
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return links
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := tokenizer.Token()
+			if tok.Data != "link" {
+				continue
+			}
+
+			var rel, typ, href string
+			for _, attr := range tok.Attr {
+				switch attr.Key {
+				case "rel":
+					rel = strings.ToLower(attr.Val)
+				case "type":
+					typ, _, _ = mime.ParseMediaType(attr.Val)
+				case "href":
+					href = strings.TrimSpace(attr.Val)
+				}
+			}
+			if href == "" || !slices.Contains(strings.Fields(rel), "alternate") || !slices.Contains(feedLinkTypes, typ) {
+				continue
+			}
+
+			ref, err := url.Parse(href)
+			if err != nil {
+				continue
+			}
+			if link := base.ResolveReference(ref).String(); !slices.Contains(links, link) {
+				links = append(links, link)
+			}
+		}
+	}
+}
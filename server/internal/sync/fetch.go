@@ -0,0 +1,257 @@
+package sync
+
+import (
+	"bufio"
+	"cmp"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// Defaults for the [FetcherOptions] left unset.
+const (
+	DefaultUserAgent      = "seymour/1.0 (+https://github.com/jdholdren/seymour)"
+	DefaultMaxBodySize    = 10 << 20
+	DefaultConnectTimeout = 5 * time.Second
+	DefaultReadTimeout    = 10 * time.Second
+)
+
+// FetcherOptions configures how a [Fetcher] makes its requests.
+type FetcherOptions struct {
+	UserAgent   string
+	MaxBodySize int64 // In bytes, after decompression
+
+	ConnectTimeout time.Duration // Dialing and the TLS handshake, including through a proxy
+	ReadTimeout    time.Duration // Waiting on the response headers, and then again for the whole body
+
+	// An http, https, or socks5 proxy to send requests through.
+	// Without one, the usual HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment variables apply.
+	ProxyURL string
+
+	// Keeps requests off the server's own network, nil allows anything.
+	Guard *Guard
+
+	// Replaces the network entirely, e.g. in tests. The proxy, guard, and connect timeout don't apply to it.
+	Transport http.RoundTripper
+
+	// Where entries can come from besides actual feeds, nil for [DefaultSources].
+	Sources []Source
+}
+
+// StatusError is a response that didn't have what was asked for, going by its status code.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// Temporary reports whether a failed fetch could go through if it were tried again shortly, like
+// after a timeout, a dropped connection, or a server error. Anything else, like a 404 or a feed
+// that doesn't parse, fails the same way until the feed itself changes.
+func Temporary(err error) bool {
+	if statusErr := (&StatusError{}); errors.As(err, &statusErr) {
+		code := statusErr.StatusCode
+		return code >= 500 || code == http.StatusRequestTimeout
+	}
+	if errors.Is(err, ErrBlockedAddress) {
+		return false
+	}
+
+	// The read timeout cancels the request's context
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Fetcher makes the requests for syncing and discovering feeds.
+type Fetcher struct {
+	client      *http.Client
+	userAgent   string
+	maxBodySize int64
+	readTimeout time.Duration
+	sources     map[seymour.FeedKind]Source
+
+	// Sent with every request, see [Fetcher.WithAuth]
+	creds seymour.FeedCredentials
+}
+
+// NewFetcher creates a fetcher, filling in defaults for any options left at zero.
+func NewFetcher(opts FetcherOptions) (*Fetcher, error) {
+	connectTimeout := cmp.Or(opts.ConnectTimeout, DefaultConnectTimeout)
+	readTimeout := cmp.Or(opts.ReadTimeout, DefaultReadTimeout)
+
+	transport := opts.Transport
+	if transport == nil {
+		var (
+			proxy   func(*http.Request) (*url.URL, error)
+			proxies []string
+			guard   = opts.Guard
+		)
+		switch env := httpproxy.FromEnvironment(); {
+		case opts.ProxyURL != "":
+			u, err := url.Parse(opts.ProxyURL)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing proxy url: %w", err)
+			}
+			switch u.Scheme {
+			case "http", "https", "socks5", "socks5h":
+			default:
+				return nil, fmt.Errorf("unsupported proxy scheme: %q", u.Scheme)
+			}
+			proxy = http.ProxyURL(u)
+			proxies = []string{u.Hostname()}
+		case env.HTTPProxy != "" || env.HTTPSProxy != "":
+			envProxy := env.ProxyFunc()
+			proxy = func(req *http.Request) (*url.URL, error) {
+				return envProxy(req.URL)
+			}
+			for _, raw := range []string{env.HTTPProxy, env.HTTPSProxy} {
+				if host := proxyHost(raw); host != "" {
+					proxies = append(proxies, host)
+				}
+			}
+		}
+		// The proxies themselves are trusted, it's where the requests are going that gets checked
+		for _, host := range proxies {
+			guard = guard.allowingHost(host)
+		}
+
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.Proxy = proxy
+		t.DialContext = guard.DialContext(&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second})
+		t.TLSHandshakeTimeout = connectTimeout
+		t.ResponseHeaderTimeout = readTimeout
+		// Bodies are decoded by the fetcher, which also knows about brotli
+		t.DisableCompression = true
+		transport = t
+		if len(proxies) > 0 && guard != nil {
+			transport = proxiedTransport{guard: opts.Guard, next: t}
+		}
+	}
+
+	sources := opts.Sources
+	if sources == nil {
+		sources = DefaultSources()
+	}
+	byKind := make(map[seymour.FeedKind]Source, len(sources))
+	for _, src := range sources {
+		byKind[src.Kind()] = src
+	}
+
+	return &Fetcher{
+		client:      &http.Client{Transport: transport},
+		userAgent:   cmp.Or(opts.UserAgent, DefaultUserAgent),
+		maxBodySize: cmp.Or(opts.MaxBodySize, DefaultMaxBodySize),
+		readTimeout: readTimeout,
+		sources:     byKind,
+	}, nil
+}
+
+// proxyHost is the hostname of a proxy from the environment, which can leave off the scheme.
+func proxyHost(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+
+	return u.Hostname()
+}
+
+// do makes the request and reads the whole decoded body of the response, whatever its status.
+//
+// The returned response's body has already been read and closed.
+func (f *Fetcher) do(req *http.Request) (*http.Response, []byte, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	applyCredentials(req, f.creds)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if len(f.creds.Query) > 0 {
+			err = redactURLError(err)
+		}
+		return nil, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// The headers are in, the body gets the read timeout to itself
+	timer := time.AfterFunc(f.readTimeout, cancel)
+	defer timer.Stop()
+
+	body, err := decodeBody(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// One extra byte to tell a body that's exactly the limit from one that's over it
+	data, err := io.ReadAll(io.LimitReader(body, f.maxBodySize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	if int64(len(data)) > f.maxBodySize {
+		return nil, nil, fmt.Errorf("response body is larger than %d bytes", f.maxBodySize)
+	}
+
+	return resp, data, nil
+}
+
+// decodeBody undoes the Content-Encoding of the response's body.
+func decodeBody(resp *http.Response) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding gzip body: %w", err)
+		}
+		return r, nil
+	case "deflate":
+		// Meant to be zlib wrapped, but plenty of servers send raw deflate instead
+		br := bufio.NewReader(resp.Body)
+		if header, err := br.Peek(2); err == nil && isZlibHeader(header) {
+			r, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding deflate body: %w", err)
+			}
+			return r, nil
+		}
+		return flate.NewReader(br), nil
+	case "br":
+		return brotli.NewReader(resp.Body), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", resp.Header.Get("Content-Encoding"))
+	}
+}
+
+// isZlibHeader checks for the two byte header zlib starts a stream with.
+func isZlibHeader(header []byte) bool {
+	return header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
@@ -0,0 +1,144 @@
+package sync
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// GitHubSource follows a repository's releases, or its tags, through the GitHub API.
+//
+// Its urls look like github:owner/repo for releases and github:owner/repo/tags for tags. A token in
+// the feed's credentials raises the API's rate limit, and lets it follow private repositories.
+type GitHubSource struct {
+	APIURL string // Defaults to https://api.github.com
+}
+
+// What GitHub allows in the name of an owner or a repository.
+var githubNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+type githubRelease struct {
+	ID          int64      `json:"id"`
+	TagName     string     `json:"tag_name"`
+	Name        string     `json:"name"`
+	HTMLURL     string     `json:"html_url"`
+	Body        string     `json:"body"`
+	BodyHTML    string     `json:"body_html"`
+	BodyText    string     `json:"body_text"`
+	Draft       bool       `json:"draft"`
+	Prerelease  bool       `json:"prerelease"`
+	CreatedAt   time.Time  `json:"created_at"`
+	PublishedAt *time.Time `json:"published_at"`
+	Author      struct {
+		Login string `json:"login"`
+	} `json:"author"`
+}
+
+type githubTag struct {
+	Name   string `json:"name"`
+	Commit struct {
+		SHA string `json:"sha"`
+	} `json:"commit"`
+}
+
+func (GitHubSource) Kind() seymour.FeedKind { return "github" }
+
+func (s GitHubSource) Fetch(ctx context.Context, f *Fetcher, feed seymour.Feed) (seymour.Feed, []seymour.FeedEntry, error) {
+	path, _, err := sourcePath(feed.URL)
+	if err != nil {
+		return seymour.Feed{}, nil, err
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || !validGitHubName(parts[0]) || !validGitHubName(parts[1]) || (len(parts) == 3 && parts[2] != "tags" && parts[2] != "releases") {
+		return seymour.Feed{}, nil, fmt.Errorf("github urls look like github:owner/repo or github:owner/repo/tags, got %q", feed.URL)
+	}
+	var (
+		repo    = parts[0] + "/" + parts[1]
+		repoURL = "https://github.com/" + repo
+	)
+
+	if len(parts) == 3 && parts[2] == "tags" {
+		var tags []githubTag
+		if err := s.get(ctx, f, repo, "tags", &tags); err != nil {
+			return seymour.Feed{}, nil, err
+		}
+
+		var entries []seymour.FeedEntry
+		for _, tag := range tags {
+			link := fmt.Sprintf("%s/releases/tag/%s", repoURL, url.PathEscape(tag.Name))
+			entries = append(entries, seymour.FeedEntry{
+				GUID:        link,
+				Title:       sanitize(tag.Name),
+				Description: sanitize(fmt.Sprintf("%s tagged %s at %.7s", repo, tag.Name, tag.Commit.SHA)),
+				Link:        link,
+			})
+		}
+
+		title, description := repo+" tags", "New tags in "+repo+" on GitHub"
+		return seymour.Feed{Title: &title, Description: &description}, entries, nil
+	}
+
+	var releases []githubRelease
+	if err := s.get(ctx, f, repo, "releases", &releases); err != nil {
+		return seymour.Feed{}, nil, err
+	}
+
+	var entries []seymour.FeedEntry
+	for _, release := range releases {
+		if release.Draft {
+			continue
+		}
+
+		published := release.CreatedAt
+		if release.PublishedAt != nil {
+			published = *release.PublishedAt
+		}
+		var categories []string
+		if release.Prerelease {
+			categories = append(categories, "prerelease")
+		}
+
+		entries = append(entries, seymour.FeedEntry{
+			GUID:        release.HTMLURL,
+			Title:       sanitize(cmp.Or(release.Name, release.TagName)),
+			Description: sanitize(cmp.Or(release.BodyText, release.Body)),
+			Content:     sanitizeHTML(release.BodyHTML),
+			Link:        release.HTMLURL,
+			PublishTime: seymour.DBTime{Time: published},
+			Authors:     uniqueNonEmpty([]string{release.Author.Login}),
+			Categories:  categories,
+		})
+	}
+
+	title, description := repo+" releases", "Releases of "+repo+" on GitHub"
+	return seymour.Feed{Title: &title, Description: &description}, entries, nil
+}
+
+func validGitHubName(name string) bool {
+	return githubNameRe.MatchString(name) && name != "." && name != ".."
+}
+
+// get requests the list of the repo's releases or tags.
+func (s GitHubSource) get(ctx context.Context, f *Fetcher, repo, list string, v any) error {
+	u := fmt.Sprintf("%s/repos/%s/%s", strings.TrimSuffix(cmp.Or(s.APIURL, "https://api.github.com"), "/"), repo, list)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("error creating github request: %w", err)
+	}
+	// Bodies as both html and plain text, along with the markdown
+	req.Header.Set("Accept", "application/vnd.github.full+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	if err := f.GetJSON(req, v); err != nil {
+		return fmt.Errorf("error getting github %s: %w", list, err)
+	}
+
+	return nil
+}
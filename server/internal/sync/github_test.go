@@ -0,0 +1,77 @@
+package sync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+func TestGitHubSource(t *testing.T) {
+	var got *http.Request
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/golang/go/releases", func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		_, _ = w.Write([]byte(`[
+  {"id": 2, "tag_name": "v1.1.0", "name": "", "html_url": "https://github.com/golang/go/releases/tag/v1.1.0", "draft": true, "created_at": "2024-02-01T00:00:00Z"},
+  {"id": 1, "tag_name": "v1.0.0", "name": "First", "html_url": "https://github.com/golang/go/releases/tag/v1.0.0",
+   "body": "**Big** release", "body_html": "<p><strong>Big</strong> release</p>", "body_text": "Big release",
+   "prerelease": true, "created_at": "2024-01-01T00:00:00Z", "published_at": "2024-01-02T00:00:00Z", "author": {"login": "gopher"}}
+]`))
+	})
+	mux.HandleFunc("/repos/golang/go/tags", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"name": "v1.0.0", "commit": {"sha": "abcdef1234567890"}}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f, err := NewFetcher(FetcherOptions{Sources: []Source{GitHubSource{APIURL: srv.URL}}})
+	require.NoError(t, err)
+
+	t.Run("releases", func(t *testing.T) {
+		feed, entries, err := f.WithAuth(seymour.FeedCredentials{Token: "tok"}).Feed(context.Background(), seymour.Feed{ID: "feed-gh", URL: "github:golang/go", Kind: "github"})
+		require.NoError(t, err)
+
+		assert.Equal(t, "Bearer tok", got.Header.Get("Authorization"))
+		assert.Equal(t, "application/vnd.github.full+json", got.Header.Get("Accept"))
+		assert.Equal(t, "golang/go releases", *feed.Title)
+		assert.NotNil(t, feed.NextSyncAt)
+
+		// Drafts are left out
+		require.Len(t, entries, 1)
+		assert.Equal(t, "feed-gh", entries[0].FeedID)
+		assert.Equal(t, "First", entries[0].Title)
+		assert.Equal(t, "Big release", entries[0].Description)
+		assert.Equal(t, "<p><strong>Big</strong> release</p>", entries[0].Content)
+		assert.Equal(t, "https://github.com/golang/go/releases/tag/v1.0.0", entries[0].Link)
+		assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), entries[0].PublishTime.Time.UTC())
+		assert.Equal(t, []string{"gopher"}, entries[0].Authors)
+		assert.Equal(t, []string{"prerelease"}, entries[0].Categories)
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		feed, entries, err := f.Feed(context.Background(), seymour.Feed{ID: "feed-gh", URL: "github:golang/go/tags", Kind: "github"})
+		require.NoError(t, err)
+
+		assert.Equal(t, "golang/go tags", *feed.Title)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "v1.0.0", entries[0].Title)
+		assert.Equal(t, "https://github.com/golang/go/releases/tag/v1.0.0", entries[0].Link)
+		assert.Contains(t, entries[0].Description, "abcdef1")
+		// Tags aren't dated, so they're as new as when they were seen
+		assert.WithinDuration(t, time.Now(), entries[0].PublishTime.Time, time.Minute)
+	})
+
+	t.Run("bad urls", func(t *testing.T) {
+		for _, u := range []string{"github:golang", "github:../../users/x", "github:golang/go/issues"} {
+			_, _, err := f.Feed(context.Background(), seymour.Feed{ID: "feed-gh", URL: u, Kind: "github"})
+			assert.Error(t, err, u)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned when a request would connect to an address the [Guard] doesn't allow.
+var ErrBlockedAddress = errors.New("address is not allowed")
+
+// Ranges that aren't caught by the netip helpers but still aren't the public internet.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // Reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which can reach any IPv4 address
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// Guard keeps fetches from reaching the server's own network: loopback, link-local (e.g. cloud
+// metadata), private, and other non-public addresses are refused unless explicitly allowed.
+//
+// It's checked when connecting, against the address a hostname actually resolved to, so it also
+// covers redirects and hostnames that resolve to internal addresses.
+//
+// A nil guard allows everything.
+type Guard struct {
+	prefixes []netip.Prefix
+	hosts    map[string]bool
+}
+
+// NewGuard creates a guard that also allows the given entries, e.g. for self-hosted feeds.
+//
+// Each entry is an IP address, a CIDR range, or a hostname. Hostnames are matched as requested,
+// not by what they resolve to.
+func NewGuard(allow []string) (*Guard, error) {
+	g := &Guard{hosts: map[string]bool{}}
+	for _, entry := range allow {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			g.prefixes = append(g.prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			g.prefixes = append(g.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		if strings.ContainsAny(entry, "/:") {
+			return nil, fmt.Errorf("invalid allowed address: %q", entry)
+		}
+		g.hosts[strings.ToLower(strings.TrimSuffix(entry, "."))] = true
+	}
+
+	return g, nil
+}
+
+// Allowed checks if connecting to the address is allowed.
+func (g *Guard) Allowed(addr netip.Addr) bool {
+	if g == nil {
+		return true
+	}
+
+	addr = addr.Unmap()
+	for _, prefix := range g.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return public(addr)
+}
+
+// public checks if the address is out on the internet, rather than on some local or reserved network.
+func public(addr netip.Addr) bool {
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DialContext wraps the dialer so it refuses to connect to addresses the guard doesn't allow.
+func (g *Guard) DialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	if g == nil {
+		return dialer.DialContext
+	}
+
+	guarded := *dialer
+	guarded.Control = func(network, address string, _ syscall.RawConn) error {
+		// By now the address is the resolved ip, whatever hostname was asked for
+		addrPort, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+		}
+		if !g.Allowed(addrPort.Addr()) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, addrPort.Addr())
+		}
+
+		return nil
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err == nil && g.hosts[strings.ToLower(strings.TrimSuffix(host, "."))] {
+			return dialer.DialContext(ctx, network, address)
+		}
+
+		return guarded.DialContext(ctx, network, address)
+	}
+}
+
+// checkHost resolves the host up front and checks every address it resolves to.
+//
+// Only needed where the guard can't see the connection itself, e.g. through a proxy.
+func (g *Guard) checkHost(ctx context.Context, host string) error {
+	if g == nil || g.hosts[strings.ToLower(strings.TrimSuffix(host, "."))] {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("error resolving host: %w", err)
+	}
+	for _, addr := range addrs {
+		if !g.Allowed(addr) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+		}
+	}
+
+	return nil
+}
+
+// proxiedTransport checks where each request is headed before handing it to the proxy.
+type proxiedTransport struct {
+	guard *Guard
+	next  http.RoundTripper
+}
+
+func (t proxiedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.guard.checkHost(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// allowingHost returns a copy of the guard that also allows the hostname.
+func (g *Guard) allowingHost(host string) *Guard {
+	if g == nil {
+		return nil
+	}
+
+	hosts := make(map[string]bool, len(g.hosts)+1)
+	for h := range g.hosts {
+		hosts[h] = true
+	}
+	hosts[strings.ToLower(host)] = true
+
+	return &Guard{prefixes: g.prefixes, hosts: hosts}
+}
+
+// Transport creates a transport for making requests outside of a [Fetcher] that still go through the guard.
+func (g *Guard) Transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil // A proxy would be all the guard ever sees
+	t.DialContext = g.DialContext(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second})
+
+	return t
+}
@@ -0,0 +1,116 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+func TestGuard_Allowed(t *testing.T) {
+	guard, err := NewGuard([]string{"10.1.0.0/16", "192.168.1.20", "feeds.lan"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		addr    string
+		allowed bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:4700::6810:85e5", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"169.254.169.254", false}, // Cloud metadata
+		{"fe80::1", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"100.64.1.1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false},
+		{"64:ff9b::7f00:1", false},
+		// Allowed explicitly
+		{"10.1.2.3", true},
+		{"192.168.1.20", true},
+		{"::ffff:192.168.1.20", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, guard.Allowed(netip.MustParseAddr(tt.addr)))
+		})
+	}
+
+	_, err = NewGuard([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+}
+
+func TestFetcher_Guard(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(testRSSFeed))
+	}))
+	defer srv.Close()
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+
+	fetch := func(allow []string, u string) error {
+		guard, err := NewGuard(allow)
+		require.NoError(t, err)
+		f, err := NewFetcher(FetcherOptions{Guard: guard})
+		require.NoError(t, err)
+
+		_, _, err = f.Feed(context.Background(), seymour.Feed{ID: "feed-guarded", URL: u})
+		return err
+	}
+
+	t.Run("loopback is blocked", func(t *testing.T) {
+		assert.ErrorIs(t, fetch(nil, srv.URL), ErrBlockedAddress)
+		assert.ErrorIs(t, fetch(nil, fmt.Sprintf("http://localhost:%d/feed", port)), ErrBlockedAddress)
+	})
+
+	t.Run("allowed by address", func(t *testing.T) {
+		assert.NoError(t, fetch([]string{"127.0.0.0/8"}, srv.URL))
+	})
+
+	t.Run("allowed by hostname", func(t *testing.T) {
+		assert.NoError(t, fetch([]string{"localhost"}, fmt.Sprintf("http://localhost:%d/feed", port)))
+	})
+
+	t.Run("redirect into a blocked address", func(t *testing.T) {
+		redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, srv.URL+"/feed", http.StatusMovedPermanently)
+		}))
+		defer redirector.Close()
+		redirectorPort := redirector.Listener.Addr().(*net.TCPAddr).Port
+
+		// The first hop is allowed by name, but it sends us on to an address that isn't
+		err := fetch([]string{"localhost"}, fmt.Sprintf("http://localhost:%d/", redirectorPort))
+		assert.ErrorIs(t, err, ErrBlockedAddress)
+	})
+
+	t.Run("through a proxy", func(t *testing.T) {
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("proxy shouldn't have been asked for %s", r.URL)
+		}))
+		defer proxy.Close()
+
+		guard, err := NewGuard(nil)
+		require.NoError(t, err)
+		f, err := NewFetcher(FetcherOptions{Guard: guard, ProxyURL: proxy.URL})
+		require.NoError(t, err)
+
+		// The proxy is on loopback itself, but it's where the request is headed that counts
+		_, _, err = f.Feed(context.Background(), seymour.Feed{ID: "feed-proxied", URL: "http://10.0.0.1/feed"})
+		assert.ErrorIs(t, err, ErrBlockedAddress)
+	})
+}
@@ -0,0 +1,86 @@
+package sync
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// HackerNewsSource follows the Hacker News stories matching a search, newest first, through the
+// Algolia HN Search API.
+//
+// Its urls are the search, like hn:golang. Anything in the url's query is passed along to the API,
+// e.g. hn:golang?numericFilters=points>100, or hn:?tags=front_page for the front page.
+type HackerNewsSource struct {
+	APIURL string // Defaults to https://hn.algolia.com
+}
+
+type hnSearchResp struct {
+	Hits []struct {
+		ObjectID  string    `json:"objectID"`
+		Title     string    `json:"title"`
+		URL       string    `json:"url"`
+		StoryText string    `json:"story_text"`
+		Author    string    `json:"author"`
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"hits"`
+}
+
+func (HackerNewsSource) Kind() seymour.FeedKind { return "hn" }
+
+func (s HackerNewsSource) Fetch(ctx context.Context, f *Fetcher, feed seymour.Feed) (seymour.Feed, []seymour.FeedEntry, error) {
+	search, query, err := sourcePath(feed.URL)
+	if err != nil {
+		return seymour.Feed{}, nil, err
+	}
+	if search == "" && len(query) == 0 {
+		return seymour.Feed{}, nil, fmt.Errorf("hacker news urls need a search, like hn:golang, got %q", feed.URL)
+	}
+	query.Set("query", search)
+	if query.Get("tags") == "" {
+		query.Set("tags", "story")
+	}
+
+	u := fmt.Sprintf("%s/api/v1/search_by_date?%s", strings.TrimSuffix(cmp.Or(s.APIURL, "https://hn.algolia.com"), "/"), query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return seymour.Feed{}, nil, fmt.Errorf("error creating hacker news request: %w", err)
+	}
+	var resp hnSearchResp
+	if err := f.GetJSON(req, &resp); err != nil {
+		return seymour.Feed{}, nil, fmt.Errorf("error searching hacker news: %w", err)
+	}
+
+	var entries []seymour.FeedEntry
+	for _, hit := range resp.Hits {
+		// Ask HN and the like link to the discussion, since that's all there is
+		discussion := "https://news.ycombinator.com/item?id=" + hit.ObjectID
+		// Not the points or comments: they change every sync, and a changed description is a revision
+		description := hit.StoryText
+		if description == "" {
+			description = "Posted to Hacker News by " + hit.Author
+		}
+
+		entries = append(entries, seymour.FeedEntry{
+			GUID:        discussion,
+			Title:       sanitize(hit.Title),
+			Description: sanitize(description),
+			Content:     sanitizeHTML(hit.StoryText),
+			Link:        cmp.Or(hit.URL, discussion),
+			PublishTime: seymour.DBTime{Time: hit.CreatedAt},
+			Authors:     uniqueNonEmpty([]string{hit.Author}),
+		})
+	}
+
+	title := "Hacker News"
+	if search != "" {
+		title += ": " + search
+	}
+	description := "Hacker News stories, newest first"
+	return seymour.Feed{Title: &title, Description: &description}, entries, nil
+}
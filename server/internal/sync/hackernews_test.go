@@ -0,0 +1,49 @@
+package sync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+func TestHackerNewsSource(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/search_by_date" {
+			http.NotFound(w, r)
+			return
+		}
+		got = r
+		_, _ = w.Write([]byte(`{"hits": [
+  {"objectID": "1", "title": "Go 2 is out", "url": "https://go.dev/blog", "author": "pg", "created_at": "2024-01-01T00:00:00.000Z", "points": 10, "num_comments": 3},
+  {"objectID": "2", "title": "Ask HN: Go?", "story_text": "<p>Why Go?</p>", "author": "dang", "created_at": "2024-01-02T00:00:00.000Z"}
+]}`))
+	}))
+	defer srv.Close()
+
+	f, err := NewFetcher(FetcherOptions{Sources: []Source{HackerNewsSource{APIURL: srv.URL}}})
+	require.NoError(t, err)
+
+	feed, entries, err := f.Feed(context.Background(), seymour.Feed{ID: "feed-hn", URL: "hn:go lang?numericFilters=points>5", Kind: "hn"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "go lang", got.URL.Query().Get("query"))
+	assert.Equal(t, "story", got.URL.Query().Get("tags"))
+	assert.Equal(t, "points>5", got.URL.Query().Get("numericFilters"))
+	assert.Equal(t, "Hacker News: go lang", *feed.Title)
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, "https://go.dev/blog", entries[0].Link)
+	assert.Equal(t, "https://news.ycombinator.com/item?id=1", entries[0].GUID)
+	assert.Equal(t, "Posted to Hacker News by pg", entries[0].Description)
+	assert.Equal(t, []string{"pg"}, entries[0].Authors)
+	// Nowhere else to link to
+	assert.Equal(t, "https://news.ycombinator.com/item?id=2", entries[1].Link)
+	assert.Equal(t, "Why Go?", entries[1].Description)
+}
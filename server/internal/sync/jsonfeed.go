@@ -0,0 +1,150 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// Represents a response from a JSON Feed fetch.
+//
+// See https://jsonfeed.org/version/1.1 for the spec.
+type jsonFeedResp struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	NextURL     string           `json:"next_url"`
+	Description string           `json:"description"`
+	Authors     []jsonFeedAuthor `json:"authors"`
+	Hubs        []struct {
+		Type string `json:"type"`
+		URL  string `json:"url"`
+	} `json:"hubs"`
+	Items []struct {
+		ID            jsonFeedID       `json:"id"`
+		URL           string           `json:"url"`
+		ExternalURL   string           `json:"external_url"`
+		Title         string           `json:"title"`
+		ContentHTML   string           `json:"content_html"`
+		ContentText   string           `json:"content_text"`
+		Summary       string           `json:"summary"`
+		DatePublished string           `json:"date_published"`
+		DateModified  string           `json:"date_modified"`
+		Image         string           `json:"image"`
+		Tags          []string         `json:"tags"`
+		Author        jsonFeedAuthor   `json:"author"` // Deprecated in 1.1, but still common
+		Authors       []jsonFeedAuthor `json:"authors"`
+		Attachments   []struct {
+			URL      string `json:"url"`
+			MIMEType string `json:"mime_type"`
+			// Numbers by the spec, but kept raw so a string or a fraction in their place doesn't sink the feed
+			Size     json.RawMessage `json:"size_in_bytes"`
+			Duration json.RawMessage `json:"duration_in_seconds"`
+		} `json:"attachments"`
+	} `json:"items"`
+}
+
+// jsonFeedID is an item's id. It's meant to be a string, but the spec has readers take a number
+// as one too, like the id of a database row.
+type jsonFeedID string
+
+func (id *jsonFeedID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = jsonFeedID(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("item id isn't a string or a number: %s", data)
+	}
+	*id = jsonFeedID(n.String())
+	return nil
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+func parseJSONFeed(feedID string, data []byte, dates *dateParser, pages *paging) (seymour.Feed, []seymour.FeedEntry, error) {
+	var feedResp jsonFeedResp
+	if err := json.Unmarshal(data, &feedResp); err != nil {
+		return seymour.Feed{}, nil, fmt.Errorf("error decoding json feed: %w", err)
+	}
+
+	entries := []seymour.FeedEntry{}
+	for _, item := range feedResp.Items {
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+
+		// Prefer the summary, but fall back to whatever content is there
+		description := item.Summary
+		if description == "" {
+			description = item.ContentHTML
+		}
+		if description == "" {
+			description = item.ContentText
+		}
+
+		publishedAt := dates.parse(item.DatePublished, item.DateModified)
+
+		media := []seymour.EntryMedia{}
+		for _, a := range item.Attachments {
+			media = append(media, seymour.EntryMedia{
+				URL:          a.URL,
+				MIMEType:     a.MIMEType,
+				Length:       parseLength(string(a.Size)),
+				Duration:     parseLength(string(a.Duration)),
+				ThumbnailURL: item.Image,
+			})
+		}
+
+		// Items inherit the feed's authors when they don't have their own
+		people := append([]jsonFeedAuthor{item.Author}, item.Authors...)
+		if item.Author.Name == "" && len(item.Authors) == 0 {
+			people = feedResp.Authors
+		}
+		var authors []string
+		for _, p := range people {
+			authors = append(authors, p.Name)
+		}
+
+		entries = append(entries, seymour.FeedEntry{
+			FeedID:      feedID,
+			GUID:        string(item.ID),
+			Title:       sanitize(item.Title),
+			Description: sanitize(description),
+			Content:     sanitizeHTML(item.ContentHTML),
+			Link:        link,
+			PublishTime: publishedAt,
+			Media:       media,
+			Authors:     uniqueNonEmpty(authors),
+			Categories:  uniqueNonEmpty(item.Tags),
+		})
+	}
+
+	// The feed's own url is the topic for any WebSub hub
+	var hub string
+	for _, h := range feedResp.Hubs {
+		if strings.EqualFold(h.Type, "websub") && h.URL != "" {
+			hub = h.URL
+			break
+		}
+	}
+
+	pages.next = strings.TrimSpace(feedResp.NextURL)
+
+	return seymour.Feed{
+		ID:          feedID,
+		Title:       &feedResp.Title,
+		Description: &feedResp.Description,
+		HubURL:      &hub,
+		TopicURL:    &feedResp.FeedURL,
+	}, entries, nil
+}
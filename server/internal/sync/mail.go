@@ -0,0 +1,129 @@
+package sync
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-message"
+	_ "github.com/emersion/go-message/charset" // Decodes the charsets newsletters actually use
+	"github.com/emersion/go-message/mail"
+	"golang.org/x/net/html"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// maxMailPartSize is how much of each part of an email is read, past what anyone would write in a newsletter.
+const maxMailPartSize = 5 << 20
+
+// ParseMail makes an entry out of an email delivered to the inbox rather than fetched, e.g. a newsletter.
+//
+// The HTML body is kept as the content, falling back to the plain text one, and attachments are
+// left out. Its Message-ID is its GUID, so a message delivered twice is only kept once.
+func ParseMail(feedID string, r io.Reader) (seymour.FeedEntry, error) {
+	mr, err := mail.CreateReader(r)
+	if err != nil && !message.IsUnknownCharset(err) {
+		return seymour.FeedEntry{}, fmt.Errorf("error reading message: %w", err)
+	}
+	defer func() { _ = mr.Close() }()
+
+	var htmlBody, textBody string
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		// A part in a charset we don't know is still mostly readable
+		if err != nil && !message.IsUnknownCharset(err) {
+			return seymour.FeedEntry{}, fmt.Errorf("error reading message part: %w", err)
+		}
+
+		header, ok := part.Header.(*mail.InlineHeader)
+		if !ok {
+			continue
+		}
+		// Only the first of each, the rest are usually forwarded or quoted messages
+		contentType, _, _ := header.ContentType()
+		switch {
+		case contentType == "text/html" && htmlBody == "":
+		case contentType == "text/plain" && textBody == "":
+		default:
+			continue
+		}
+
+		body, err := io.ReadAll(io.LimitReader(part.Body, maxMailPartSize))
+		if err != nil {
+			return seymour.FeedEntry{}, fmt.Errorf("error reading message part: %w", err)
+		}
+		if contentType == "text/html" {
+			htmlBody = string(body)
+		} else {
+			textBody = string(body)
+		}
+	}
+
+	entry := seymour.FeedEntry{
+		FeedID:          feedID,
+		ListUnsubscribe: strings.TrimSpace(mr.Header.Get("List-Unsubscribe")),
+	}
+	if entry.Title, err = mr.Header.Subject(); err != nil {
+		entry.Title = mr.Header.Get("Subject")
+	}
+	entry.Title = strings.Join(strings.Fields(entry.Title), " ")
+	if date, err := mr.Header.Date(); err == nil && !date.IsZero() {
+		entry.PublishTime.Time = date.UTC()
+	} else {
+		entry.PublishTime.Time = time.Now().UTC()
+	}
+	if from, err := mr.Header.AddressList("From"); err == nil && len(from) > 0 {
+		entry.Authors = []string{cmp.Or(from[0].Name, from[0].Address)}
+	}
+
+	entry.Content = textToHTML(textBody)
+	if htmlBody != "" {
+		entry.Content = sanitizeHTML(htmlBody)
+	}
+	entry.Description = mailDescription(entry.Content)
+
+	entry.GUID, err = mr.Header.MessageID()
+	if err != nil || entry.GUID == "" {
+		entry.GUID = synthesizeGUID(entry)
+	}
+
+	return entry, nil
+}
+
+// mailDescription is the text of the mail's content on a single line.
+func mailDescription(content string) string {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return ""
+	}
+
+	// Escaped since sanitize expects HTML, it's what keeps the length down
+	return sanitize(html.EscapeString(strings.ReplaceAll(blockText(doc), "\n", " ")))
+}
+
+// textToHTML turns a plain text email into paragraphs, keeping its line breaks.
+func textToHTML(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var b strings.Builder
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+
+		lines := strings.Split(paragraph, "\n")
+		for i := range lines {
+			lines[i] = html.EscapeString(strings.TrimSpace(lines[i]))
+		}
+		fmt.Fprintf(&b, "<p>%s</p>", strings.Join(lines, "<br>"))
+	}
+
+	return b.String()
+}
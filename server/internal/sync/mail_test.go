@@ -0,0 +1,78 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNewsletter = "From: \"The Weekly\" <hello@weekly.example>\r\n" +
+	"To: abc@inbox.local\r\n" +
+	"Subject: =?UTF-8?Q?Issue_42=3A_caf=C3=A9s?=\r\n" +
+	"Date: Sat, 02 Mar 2024 10:00:00 +0000\r\n" +
+	"Message-ID: <issue-42@weekly.example>\r\n" +
+	"List-Unsubscribe: <mailto:unsub@weekly.example>, <https://weekly.example/unsub?u=1>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"The best caf=E9s this week.\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"<html><head><style>p { color: red; }</style></head><body><p>The best <b>caf=C3=A9s</b> this week.</p><script>track()</script></body></html>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf\r\n" +
+	"Content-Disposition: attachment; filename=\"issue.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQK\r\n" +
+	"--outer--\r\n"
+
+func TestParseMail(t *testing.T) {
+	entry, err := ParseMail("feed-newsletter", strings.NewReader(testNewsletter))
+	require.NoError(t, err)
+
+	assert.Equal(t, "feed-newsletter", entry.FeedID)
+	assert.Equal(t, "issue-42@weekly.example", entry.GUID)
+	assert.Equal(t, "Issue 42: cafés", entry.Title)
+	assert.Equal(t, time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC), entry.PublishTime.Time)
+	assert.Equal(t, []string{"The Weekly"}, entry.Authors)
+	assert.Equal(t, "<mailto:unsub@weekly.example>, <https://weekly.example/unsub?u=1>", entry.ListUnsubscribe)
+
+	// The HTML wins over the plain text
+	assert.Contains(t, entry.Content, "<p>The best <b>cafés</b> this week.</p>")
+	assert.NotContains(t, entry.Content, "track()")
+	assert.Equal(t, "The best cafés this week.", entry.Description)
+
+	t.Run("plain text only", func(t *testing.T) {
+		msg := "From: hello@weekly.example\r\n" +
+			"Subject: Plain\r\n" +
+			"Content-Type: text/plain; charset=utf-8\r\n" +
+			"\r\n" +
+			"First line\r\nsecond <line>\r\n\r\nNext paragraph\r\n"
+
+		entry, err := ParseMail("feed-newsletter", strings.NewReader(msg))
+		require.NoError(t, err)
+
+		assert.Equal(t, "Plain", entry.Title)
+		assert.Equal(t, []string{"hello@weekly.example"}, entry.Authors)
+		assert.Equal(t, "<p>First line<br>second &lt;line&gt;</p><p>Next paragraph</p>", entry.Content)
+		assert.Equal(t, "First line second <line> Next paragraph", entry.Description)
+
+		// Without a Message-ID or a Date, it's told apart by what it says and when it came
+		assert.NotEmpty(t, entry.GUID)
+		assert.WithinDuration(t, time.Now(), entry.PublishTime.Time, time.Minute)
+	})
+}
@@ -0,0 +1,165 @@
+package sync
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// MastodonSource follows an account's public posts through its server's API, leaving out replies and boosts.
+//
+// Its urls are the account's handle, like mastodon:@user@example.social, or its profile,
+// like mastodon:https://example.social/@user.
+type MastodonSource struct{}
+
+type mastodonAccount struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Note        string `json:"note"`
+}
+
+type mastodonStatus struct {
+	ID          string    `json:"id"`
+	URI         string    `json:"uri"`
+	URL         string    `json:"url"`
+	CreatedAt   time.Time `json:"created_at"`
+	Content     string    `json:"content"`
+	SpoilerText string    `json:"spoiler_text"`
+	Media       []struct {
+		Type       string `json:"type"`
+		URL        string `json:"url"`
+		PreviewURL string `json:"preview_url"`
+	} `json:"media_attachments"`
+	Tags []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+}
+
+// How much of a post is used as its title, since posts don't have one.
+const mastodonTitleLen = 80
+
+func (MastodonSource) Kind() seymour.FeedKind { return "mastodon" }
+
+func (s MastodonSource) Fetch(ctx context.Context, f *Fetcher, feed seymour.Feed) (seymour.Feed, []seymour.FeedEntry, error) {
+	server, username, err := mastodonAccountURL(feed.URL)
+	if err != nil {
+		return seymour.Feed{}, nil, err
+	}
+
+	var account mastodonAccount
+	lookup := fmt.Sprintf("%s/api/v1/accounts/lookup?%s", server, url.Values{"acct": {username}}.Encode())
+	if err := s.get(ctx, f, lookup, &account); err != nil {
+		return seymour.Feed{}, nil, fmt.Errorf("error looking up mastodon account: %w", err)
+	}
+
+	var statuses []mastodonStatus
+	list := fmt.Sprintf("%s/api/v1/accounts/%s/statuses?exclude_replies=true&exclude_reblogs=true&limit=40", server, url.PathEscape(account.ID))
+	if err := s.get(ctx, f, list, &statuses); err != nil {
+		return seymour.Feed{}, nil, fmt.Errorf("error getting mastodon posts: %w", err)
+	}
+
+	name := cmp.Or(account.DisplayName, account.Username)
+	var entries []seymour.FeedEntry
+	for _, status := range statuses {
+		text := sanitize(status.Content)
+		title := sanitize(status.SpoilerText)
+		if title == "" {
+			title = truncateRunes(text, mastodonTitleLen)
+		}
+
+		media := []seymour.EntryMedia{}
+		for _, m := range status.Media {
+			if m.URL == "" {
+				continue
+			}
+			u, _ := url.Parse(m.URL)
+			var mimeType string
+			if u != nil {
+				mimeType = mime.TypeByExtension(path.Ext(u.Path))
+			}
+			media = append(media, seymour.EntryMedia{
+				URL:          m.URL,
+				MIMEType:     mimeType,
+				ThumbnailURL: m.PreviewURL,
+			})
+		}
+
+		var tags []string
+		for _, t := range status.Tags {
+			tags = append(tags, t.Name)
+		}
+
+		entries = append(entries, seymour.FeedEntry{
+			GUID:        cmp.Or(status.URI, status.URL, status.ID),
+			Title:       title,
+			Description: text,
+			Content:     sanitizeHTML(status.Content),
+			Link:        cmp.Or(status.URL, status.URI),
+			PublishTime: seymour.DBTime{Time: status.CreatedAt},
+			Media:       media,
+			Authors:     uniqueNonEmpty([]string{name}),
+			Categories:  uniqueNonEmpty(tags),
+		})
+	}
+
+	description := sanitize(account.Note)
+	return seymour.Feed{Title: &name, Description: &description}, entries, nil
+}
+
+func (MastodonSource) get(ctx context.Context, f *Fetcher, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("error creating mastodon request: %w", err)
+	}
+
+	return f.GetJSON(req, v)
+}
+
+// mastodonAccountURL splits a mastodon feed's url into the account's server and its username there.
+func mastodonAccountURL(rawURL string) (string, string, error) {
+	account, _, err := sourcePath(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	// A profile, like https://example.social/@user
+	if strings.Contains(account, "://") {
+		u, err := url.Parse(account)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", "", fmt.Errorf("invalid mastodon profile url: %q", account)
+		}
+		username := strings.TrimPrefix(strings.Trim(u.Path, "/"), "@")
+		if username == "" || strings.Contains(username, "/") {
+			return "", "", fmt.Errorf("invalid mastodon profile url: %q", account)
+		}
+
+		return u.Scheme + "://" + u.Host, username, nil
+	}
+
+	// A handle, like @user@example.social
+	username, server, ok := strings.Cut(strings.TrimPrefix(account, "@"), "@")
+	if !ok || username == "" || server == "" || strings.ContainsAny(server, "/@?#") {
+		return "", "", fmt.Errorf("mastodon urls look like mastodon:@user@example.social, got %q", rawURL)
+	}
+
+	return "https://" + server, username, nil
+}
+
+// truncateRunes cuts the string down to at most n runes, marking that it was cut.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	return strings.TrimSpace(string([]rune(s)[:n-1])) + "…"
+}
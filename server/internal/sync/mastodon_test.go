@@ -0,0 +1,75 @@
+package sync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+func TestMastodonSource(t *testing.T) {
+	var got *http.Request
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/accounts/lookup", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("acct") != "gopher" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "42", "username": "gopher", "display_name": "The Gopher", "note": "<p>Digging</p>"}`))
+	})
+	mux.HandleFunc("/api/v1/accounts/42/statuses", func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		_, _ = w.Write([]byte(`[{"id": "7", "uri": "https://example.social/users/gopher/statuses/7", "url": "https://example.social/@gopher/7",
+  "created_at": "2024-01-03T00:00:00.000Z", "content": "<p>Hello <a href=\"https://example.social/tags/go\">#go</a> world, this post goes on long enough that it needs cutting down to a title</p>",
+  "spoiler_text": "", "media_attachments": [{"type": "image", "url": "https://files.example.social/a.png", "preview_url": "https://files.example.social/a_small.png"}],
+  "tags": [{"name": "go"}]}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f, err := NewFetcher(FetcherOptions{Sources: []Source{MastodonSource{}}})
+	require.NoError(t, err)
+
+	feed, entries, err := f.Feed(context.Background(), seymour.Feed{ID: "feed-m", URL: "mastodon:" + srv.URL + "/@gopher", Kind: "mastodon"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "true", got.URL.Query().Get("exclude_replies"))
+	assert.Equal(t, "true", got.URL.Query().Get("exclude_reblogs"))
+	assert.Equal(t, "The Gopher", *feed.Title)
+	assert.Equal(t, "Digging", *feed.Description)
+
+	require.Len(t, entries, 1)
+	assert.Equal(t, "https://example.social/users/gopher/statuses/7", entries[0].GUID)
+	assert.Equal(t, "https://example.social/@gopher/7", entries[0].Link)
+	assert.True(t, strings.HasSuffix(entries[0].Title, "…"))
+	assert.LessOrEqual(t, utf8.RuneCountInString(entries[0].Title), 80)
+	assert.Equal(t, []string{"The Gopher"}, entries[0].Authors)
+	assert.Equal(t, []string{"go"}, entries[0].Categories)
+	require.Len(t, entries[0].Media, 1)
+	assert.Equal(t, "image/png", entries[0].Media[0].MIMEType)
+	assert.Equal(t, "https://files.example.social/a_small.png", entries[0].Media[0].ThumbnailURL)
+}
+
+func TestMastodonAccountURL(t *testing.T) {
+	server, username, err := mastodonAccountURL("mastodon:@gopher@example.social")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.social", server)
+	assert.Equal(t, "gopher", username)
+
+	server, username, err = mastodonAccountURL("mastodon:https://example.social/@gopher/")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.social", server)
+	assert.Equal(t, "gopher", username)
+
+	for _, u := range []string{"mastodon:gopher", "mastodon:@gopher@", "mastodon:ftp://example.social/@gopher", "mastodon:https://example.social/@gopher/7"} {
+		_, _, err := mastodonAccountURL(u)
+		assert.Error(t, err, u)
+	}
+}
@@ -0,0 +1,154 @@
+package sync
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// An RSS <enclosure>, used by podcasts for the audio file.
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// A Media RSS <media:content>.
+type mediaContent struct {
+	URL        string           `xml:"url,attr"`
+	Type       string           `xml:"type,attr"`
+	FileSize   string           `xml:"fileSize,attr"`
+	Duration   string           `xml:"duration,attr"`
+	Thumbnails []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// A Media RSS <media:thumbnail>.
+type mediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+// A Media RSS <media:group>, which YouTube uses to wrap the video and its thumbnail.
+type mediaGroup struct {
+	Contents   []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// itemMedia holds the media elements that can hang off an RSS item or Atom entry.
+//
+// It's embedded in the item structs so the elements are decoded alongside the rest.
+type itemMedia struct {
+	Enclosures      []rssEnclosure   `xml:"enclosure"`
+	MediaContents   []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnails []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroups     []mediaGroup     `xml:"http://search.yahoo.com/mrss/ group"`
+	ITunesDuration  string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ITunesImage     struct {
+		Href string `xml:"href,attr"`
+	} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+}
+
+// media flattens all of the media elements into a list of attachments, one per url.
+//
+// Any extra enclosures (e.g. Atom's rel="enclosure" links) are folded in as well.
+func (m itemMedia) media(extra ...rssEnclosure) []seymour.EntryMedia {
+	var (
+		media     = []seymour.EntryMedia{}
+		duration  = parseDuration(m.ITunesDuration)
+		thumbnail = m.ITunesImage.Href
+	)
+	if len(m.MediaThumbnails) > 0 {
+		thumbnail = m.MediaThumbnails[0].URL
+	}
+
+	add := func(em seymour.EntryMedia) {
+		if em.URL == "" || slices.ContainsFunc(media, func(existing seymour.EntryMedia) bool {
+			return existing.URL == em.URL
+		}) {
+			return
+		}
+		if em.Duration == 0 {
+			em.Duration = duration
+		}
+		if em.ThumbnailURL == "" {
+			em.ThumbnailURL = thumbnail
+		}
+
+		media = append(media, em)
+	}
+	addContents := func(contents []mediaContent, groupThumbnail string) {
+		for _, c := range contents {
+			em := seymour.EntryMedia{
+				URL:          c.URL,
+				MIMEType:     c.Type,
+				Length:       parseLength(c.FileSize),
+				Duration:     parseLength(c.Duration),
+				ThumbnailURL: groupThumbnail,
+			}
+			if len(c.Thumbnails) > 0 {
+				em.ThumbnailURL = c.Thumbnails[0].URL
+			}
+			add(em)
+		}
+	}
+
+	for _, e := range slices.Concat(m.Enclosures, extra) {
+		add(seymour.EntryMedia{
+			URL:      e.URL,
+			MIMEType: e.Type,
+			Length:   parseLength(e.Length),
+		})
+	}
+	addContents(m.MediaContents, "")
+	for _, g := range m.MediaGroups {
+		var groupThumbnail string
+		if len(g.Thumbnails) > 0 {
+			groupThumbnail = g.Thumbnails[0].URL
+		}
+		addContents(g.Contents, groupThumbnail)
+	}
+
+	return media
+}
+
+// parseDuration parses an itunes:duration, which is either a number of seconds
+// or a clock-style HH:MM:SS / MM:SS value. The seconds can have a fraction, which is
+// dropped. Returns zero if it can't be parsed.
+func parseDuration(s string) int64 {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	var total int64
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if i == len(parts)-1 {
+			secs, err := strconv.ParseFloat(part, 64)
+			if err != nil || secs < 0 || secs >= math.MaxInt64 {
+				return 0
+			}
+			return total*60 + int64(secs)
+		}
+
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		total = total*60 + n
+	}
+
+	return total
+}
+
+// parseLength parses a size in bytes or a length in seconds, which feeds fill with all kinds of
+// things besides whole numbers, e.g. "unknown" or "12.5". Returns zero if it can't be parsed.
+func parseLength(s string) int64 {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return max(n, 0)
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f >= 0 && f < math.MaxInt64 {
+		return int64(f)
+	}
+
+	return 0
+}
@@ -0,0 +1,67 @@
+package sync
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// Represents a response from an RSS 1.0 (RDF) feed fetch.
+//
+// Unlike RSS 2.0, the items are siblings of the channel rather than children,
+// and item metadata comes from the Dublin Core namespace.
+type rdfFeedResp struct {
+	XMLName xml.Name `xml:"RDF"`
+	Channel struct {
+		Title       string `xml:"title"`
+		Description string `xml:"description"`
+		Link        string `xml:"link"`
+	} `xml:"channel"`
+	Items []struct {
+		About       string   `xml:"about,attr"`
+		Title       string   `xml:"title"`
+		Link        string   `xml:"link"`
+		Description string   `xml:"description"`
+		Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+		Creators    []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+		Encoded     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	} `xml:"item"`
+}
+
+func parseRDF(feedID string, data []byte, dates *dateParser) (seymour.Feed, []seymour.FeedEntry, error) {
+	var feedResp rdfFeedResp
+	if err := newXMLDecoder(data).Decode(&feedResp); err != nil {
+		return seymour.Feed{}, nil, fmt.Errorf("error decoding rdf feed: %w", err)
+	}
+
+	entries := []seymour.FeedEntry{}
+	for _, item := range feedResp.Items {
+		// Items are identified by their rdf:about, which is usually the link
+		guid := item.About
+		if guid == "" {
+			guid = item.Link
+		}
+
+		publishedAt := dates.parse(item.Date)
+
+		entries = append(entries, seymour.FeedEntry{
+			FeedID:      feedID,
+			GUID:        guid,
+			Title:       sanitize(item.Title),
+			Description: sanitize(item.Description),
+			Content:     sanitizeHTML(item.Encoded),
+			Link:        item.Link,
+			PublishTime: publishedAt,
+			Authors:     uniqueNonEmpty(item.Creators),
+			Categories:  uniqueNonEmpty(item.Subjects),
+		})
+	}
+
+	return seymour.Feed{
+		ID:          feedID,
+		Title:       &feedResp.Channel.Title,
+		Description: &feedResp.Channel.Description,
+	}, entries, nil
+}
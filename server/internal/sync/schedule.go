@@ -0,0 +1,213 @@
+package sync
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// Bounds on how often a feed is fetched, regardless of what it says about itself.
+const (
+	minSyncInterval     = 10 * time.Minute
+	maxSyncInterval     = 24 * time.Hour
+	defaultSyncInterval = time.Hour
+
+	// The longest a Retry-After is honored for.
+	maxRetryAfter = 7 * 24 * time.Hour
+	// How long a 429 or 503 without a usable Retry-After is left alone for.
+	defaultRetryAfter = minSyncInterval
+
+	// How many of the latest entries are used to work out how often a feed posts.
+	frequencyWindow = 10
+)
+
+// RetryAfterError is returned by [Feed] when the server asks to be left alone for a while,
+// e.g. a 429 or 503, for as long as its Retry-After header says if it has one.
+type RetryAfterError struct {
+	StatusCode int
+	Until      time.Time
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d, retry after %s", e.StatusCode, e.Until.Format(time.RFC3339))
+}
+
+// schedule collects what a feed and the server hosting it say about how often it should be fetched.
+type schedule struct {
+	ttl       time.Duration // RSS <ttl>
+	maxAge    time.Duration // Cache-Control: max-age
+	skipHours []int         // RSS <skipHours>, in GMT
+	skipDays  []time.Weekday
+	published []time.Time // Publish times given by the feed, for its posting frequency
+}
+
+// interval works out how long to wait between fetches.
+//
+// It's half the average time between the latest entries (counting up to now, so a feed
+// that's gone quiet slows down), but never sooner than the feed or server asked for.
+func (s *schedule) interval(now time.Time) time.Duration {
+	interval := defaultSyncInterval
+
+	published := slices.DeleteFunc(slices.Clone(s.published), func(t time.Time) bool {
+		return t.After(now)
+	})
+	if len(published) > 0 {
+		slices.SortFunc(published, func(a, b time.Time) int { return b.Compare(a) })
+		published = published[:min(len(published), frequencyWindow)]
+
+		oldest := published[len(published)-1]
+		interval = now.Sub(oldest) / time.Duration(len(published)) / 2
+	}
+
+	interval = max(interval, s.ttl, s.maxAge)
+	return min(max(interval, minSyncInterval), maxSyncInterval)
+}
+
+// next returns when the feed should next be fetched, moved out of any hours or days it asks to be skipped.
+func (s *schedule) next(now time.Time, interval time.Duration) time.Time {
+	next := now.Add(interval).UTC()
+
+	// A week of hours is every combination, so it only runs out if everything is skipped
+	for candidate, i := next, 0; i < 7*24; i++ {
+		if !slices.Contains(s.skipHours, candidate.Hour()) && !slices.Contains(s.skipDays, candidate.Weekday()) {
+			return candidate
+		}
+		candidate = candidate.Truncate(time.Hour).Add(time.Hour)
+	}
+
+	return next
+}
+
+// Reschedule works out the next sync for a feed that hasn't changed, using the interval from its last
+// full sync and keeping out of the hours and days it asked to be skipped then. A max-age the server gave
+// this time, zero if none, holds it back further.
+func Reschedule(feed seymour.Feed, maxAge time.Duration, now time.Time) seymour.DBTime {
+	interval := time.Duration(feed.SyncInterval) * time.Second
+	if interval == 0 {
+		interval = defaultSyncInterval
+	}
+	interval = min(max(interval, maxAge), maxSyncInterval)
+
+	return seymour.DBTime{Time: feedSkips(feed).next(now, interval)}
+}
+
+// Backoff works out the next sync for a feed that's failed the given number of times in a row.
+//
+// The wait doubles with each failure, up to the longest interval between syncs.
+func Backoff(failures int, now time.Time) seymour.DBTime {
+	backoff := maxSyncInterval
+	if failures < 16 {
+		backoff = min(minSyncInterval<<max(failures-1, 0), maxSyncInterval)
+	}
+
+	return seymour.DBTime{Time: now.Add(backoff).UTC()}
+}
+
+// maxAge reads the max-age from a Cache-Control header, zero if there isn't a usable one.
+func maxAge(header http.Header) time.Duration {
+	var age time.Duration
+	for directive := range strings.SplitSeq(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache", "no-store":
+			return 0
+		case "max-age":
+			if secs, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64); err == nil && secs > 0 {
+				age = time.Duration(secs) * time.Second
+			}
+		}
+	}
+
+	return age
+}
+
+// retryAfter reads a Retry-After header, which is either a number of seconds or an HTTP date.
+func retryAfter(header http.Header, now time.Time) (time.Time, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+
+	var until time.Time
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		until = now.Add(time.Duration(secs) * time.Second)
+	} else if t, err := http.ParseTime(value); err == nil {
+		until = t
+	} else {
+		return time.Time{}, false
+	}
+
+	if limit := now.Add(maxRetryAfter); until.After(limit) {
+		until = limit
+	}
+
+	return until.UTC(), true
+}
+
+// rssTTL parses an RSS <ttl>, ignoring it if it isn't a number of minutes.
+func rssTTL(ttl string) time.Duration {
+	minutes, err := strconv.Atoi(strings.TrimSpace(ttl))
+	if err != nil || minutes < 0 {
+		return 0
+	}
+
+	return time.Duration(minutes) * time.Minute
+}
+
+// skipHours checks the hours of an RSS <skipHours>, ignoring any that aren't hours.
+//
+// Some feeds count from 1 to 24, so 24 is taken as midnight.
+func skipHours(hours []string) []int {
+	valid := []int{}
+	for _, hour := range hours {
+		h, err := strconv.Atoi(strings.TrimSpace(hour))
+		if err == nil && h >= 0 && h <= 24 && !slices.Contains(valid, h%24) {
+			valid = append(valid, h%24)
+		}
+	}
+
+	return valid
+}
+
+// skipDays converts the day names of an RSS <skipDays>, ignoring any that aren't days.
+func skipDays(days []string) []time.Weekday {
+	weekdays := []time.Weekday{}
+	for _, day := range days {
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if strings.EqualFold(strings.TrimSpace(day), wd.String()) {
+				weekdays = append(weekdays, wd)
+			}
+		}
+	}
+
+	return weekdays
+}
+
+// skips gives the hours and days to skip, comma separated, to be kept on the feed for [Reschedule].
+func (s *schedule) skips() (hours, days string) {
+	hourList := make([]string, 0, len(s.skipHours))
+	for _, h := range s.skipHours {
+		hourList = append(hourList, strconv.Itoa(h))
+	}
+	dayList := make([]string, 0, len(s.skipDays))
+	for _, wd := range s.skipDays {
+		dayList = append(dayList, wd.String())
+	}
+
+	return strings.Join(hourList, ","), strings.Join(dayList, ",")
+}
+
+// feedSkips reads back the hours and days to skip that were kept on the feed.
+func feedSkips(feed seymour.Feed) *schedule {
+	sched := &schedule{}
+	if feed.SkipHours != nil && *feed.SkipHours != "" {
+		sched.skipHours = skipHours(strings.Split(*feed.SkipHours, ","))
+	}
+	if feed.SkipDays != nil && *feed.SkipDays != "" {
+		sched.skipDays = skipDays(strings.Split(*feed.SkipDays, ","))
+	}
+
+	return sched
+}
@@ -0,0 +1,243 @@
+package sync
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// ScrapeSource makes a feed out of a page that doesn't have one, picking its entries out with
+// the selectors of the feed's [seymour.ScrapeConfig].
+//
+// Its urls are the page's with scrape: in front, like scrape:https://example.com/changelog.
+type ScrapeSource struct{}
+
+func (ScrapeSource) Kind() seymour.FeedKind { return "scrape" }
+
+func (ScrapeSource) Fetch(ctx context.Context, f *Fetcher, feed seymour.Feed) (seymour.Feed, []seymour.FeedEntry, error) {
+	if feed.SourceConfig == nil {
+		return seymour.Feed{}, nil, errors.New("scraped feed has no selectors")
+	}
+	var cfg seymour.ScrapeConfig
+	if err := json.Unmarshal([]byte(*feed.SourceConfig), &cfg); err != nil {
+		return seymour.Feed{}, nil, fmt.Errorf("error decoding scrape config: %w", err)
+	}
+
+	return f.Scrape(ctx, strings.TrimPrefix(feed.URL, "scrape:"), cfg)
+}
+
+// Selectors for the parts of the page that aren't configured.
+var (
+	titleSel       = cascadia.MustCompile("title")
+	bodySel        = cascadia.MustCompile("body")
+	descriptionSel = cascadia.MustCompile(`meta[name="description"]`)
+	hrefSel        = cascadia.MustCompile("[href]")
+)
+
+// scraper is a [seymour.ScrapeConfig] with its selectors compiled, nil for the ones left out.
+type scraper struct {
+	item, title, link, date, summary cascadia.Sel
+}
+
+// compileScrape checks the config's selectors, compiling them.
+func compileScrape(cfg seymour.ScrapeConfig) (scraper, error) {
+	if strings.TrimSpace(cfg.Item) == "" {
+		return scraper{}, errors.New("an item selector is required")
+	}
+
+	var s scraper
+	for _, sel := range []struct {
+		name     string
+		selector string
+		into     *cascadia.Sel
+	}{
+		{"item", cfg.Item, &s.item},
+		{"title", cfg.Title, &s.title},
+		{"link", cfg.Link, &s.link},
+		{"date", cfg.Date, &s.date},
+		{"summary", cfg.Summary, &s.summary},
+	} {
+		if strings.TrimSpace(sel.selector) == "" {
+			continue
+		}
+
+		compiled, err := cascadia.Parse(sel.selector)
+		if err != nil {
+			return scraper{}, fmt.Errorf("invalid %s selector: %w", sel.name, err)
+		}
+		*sel.into = compiled
+	}
+
+	return s, nil
+}
+
+// ValidateScrapeConfig checks that the config has an item selector and that all of its selectors parse.
+func ValidateScrapeConfig(cfg seymour.ScrapeConfig) error {
+	_, err := compileScrape(cfg)
+	return err
+}
+
+// Scrape fetches the page and picks its entries out with the config's selectors.
+//
+// Entries keep the page's order, and any without a date are left without one.
+func (f *Fetcher) Scrape(ctx context.Context, pageURL string, cfg seymour.ScrapeConfig) (seymour.Feed, []seymour.FeedEntry, error) {
+	s, err := compileScrape(cfg)
+	if err != nil {
+		return seymour.Feed{}, nil, err
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return seymour.Feed{}, nil, fmt.Errorf("error parsing url: %w", err)
+	}
+
+	page, err := f.get(ctx, pageURL)
+	if err != nil {
+		return seymour.Feed{}, nil, err
+	}
+	body, err := toUTF8(page.contentType, page.body)
+	if err != nil {
+		return seymour.Feed{}, nil, err
+	}
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return seymour.Feed{}, nil, fmt.Errorf("error parsing page: %w", err)
+	}
+
+	var (
+		dates   = &dateParser{}
+		entries = []seymour.FeedEntry{}
+		guids   []string
+	)
+	for _, item := range cascadia.QueryAll(doc, s.item) {
+		entry := seymour.FeedEntry{Title: nodeText(item)}
+		if s.title != nil {
+			entry.Title = nodeText(cascadia.Query(item, s.title))
+		}
+		if s.summary != nil {
+			if summary := cascadia.Query(item, s.summary); summary != nil {
+				entry.Description = strings.Join(strings.Fields(sanitize(renderChildren(summary))), " ")
+				entry.Content = sanitizeHTML(renderChildren(summary))
+			}
+		}
+		if s.date != nil {
+			if date := cascadia.Query(item, s.date); date != nil {
+				entry.PublishTime = dates.parse(firstAttr(date, "datetime", "content"), nodeText(date))
+			}
+		}
+
+		linkNode := firstLink(item)
+		if s.link != nil {
+			linkNode = firstLink(cascadia.Query(item, s.link))
+		}
+		if href := attr(linkNode, "href"); href != "" {
+			if ref, err := url.Parse(href); err == nil {
+				entry.Link = base.ResolveReference(ref).String()
+			}
+		}
+
+		if entry.Title == "" && entry.Link == "" {
+			continue
+		}
+
+		// Items all linking to the page itself, or to the same place, need something else to tell them apart
+		entry.GUID = entry.Link
+		if entry.Link == "" || entry.Link == base.String() || slices.Contains(guids, entry.GUID) {
+			entry.GUID = synthesizeGUID(entry)
+		}
+		entry.Link = cmp.Or(entry.Link, base.String())
+		guids = append(guids, entry.GUID)
+
+		entries = append(entries, entry)
+	}
+
+	title := nodeText(cascadia.Query(doc, titleSel))
+	description := attr(cascadia.Query(doc, descriptionSel), "content")
+	return seymour.Feed{
+		Title:        &title,
+		Description:  &description,
+		ParseWarning: dates.warning(),
+	}, entries, nil
+}
+
+// nodeText is the node's text with its whitespace collapsed, empty for a nil node.
+func nodeText(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+			b.WriteString(" ")
+		case n.Type == html.ElementNode && (n.DataAtom == atom.Script || n.DataAtom == atom.Style):
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// renderChildren renders what's inside the node back to HTML.
+func renderChildren(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&b, c); err != nil {
+			return ""
+		}
+	}
+
+	return b.String()
+}
+
+// firstLink is the node if it's a link, otherwise the first link inside of it.
+func firstLink(n *html.Node) *html.Node {
+	if n == nil || attr(n, "href") != "" {
+		return n
+	}
+
+	return cascadia.Query(n, hrefSel)
+}
+
+// attr gets the attribute's value from the node, empty for a nil node.
+func attr(n *html.Node, key string) string {
+	if n == nil {
+		return ""
+	}
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+
+	return ""
+}
+
+// firstAttr gets the first of the attributes the node has.
+func firstAttr(n *html.Node, keys ...string) string {
+	for _, key := range keys {
+		if v := attr(n, key); v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
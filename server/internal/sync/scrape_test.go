@@ -0,0 +1,103 @@
+package sync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+const testScrapePage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Vendor Changelog</title>
+  <meta name="description" content="What changed">
+</head>
+<body>
+  <ul class="changes">
+    <li class="change">
+      <h2><a href="/changelog/2">Version 2</a></h2>
+      <time datetime="2024-03-02">March 2nd</time>
+      <div class="summary"><p>Faster <b>everything</b>.</p></div>
+    </li>
+    <li class="change">
+      <h2>Version 1</h2>
+      <time>January 5, 2024</time>
+      <div class="summary">
+        First   release.
+      </div>
+    </li>
+    <li class="change"></li>
+  </ul>
+</body>
+</html>`
+
+func TestFetcher_Scrape(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(testScrapePage))
+	}))
+	defer srv.Close()
+
+	cfg := seymour.ScrapeConfig{Item: "li.change", Title: "h2", Date: "time", Summary: ".summary"}
+	feed, entries, err := testFetcher.Scrape(context.Background(), srv.URL+"/changelog", cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Vendor Changelog", *feed.Title)
+	assert.Equal(t, "What changed", *feed.Description)
+	assert.Nil(t, feed.ParseWarning)
+
+	// The empty item's left out
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "Version 2", entries[0].Title)
+	assert.Equal(t, srv.URL+"/changelog/2", entries[0].Link)
+	assert.Equal(t, srv.URL+"/changelog/2", entries[0].GUID)
+	assert.Equal(t, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), entries[0].PublishTime.Time)
+	assert.Equal(t, "Faster everything.", entries[0].Description)
+	assert.Equal(t, "<p>Faster <b>everything</b>.</p>", entries[0].Content)
+
+	// Nothing to link to but the page, so it's told apart some other way
+	assert.Equal(t, "Version 1", entries[1].Title)
+	assert.Equal(t, srv.URL+"/changelog", entries[1].Link)
+	assert.NotEqual(t, entries[1].Link, entries[1].GUID)
+	assert.Equal(t, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), entries[1].PublishTime.Time)
+	assert.Equal(t, "First release.", entries[1].Description)
+
+	t.Run("as a source", func(t *testing.T) {
+		config := `{"item": "li.change", "title": "h2"}`
+		_, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{
+			ID:           "feed-scraped",
+			URL:          "scrape:" + srv.URL + "/changelog",
+			Kind:         "scrape",
+			SourceConfig: &config,
+		})
+		require.NoError(t, err)
+
+		require.Len(t, entries, 2)
+		assert.Equal(t, "feed-scraped", entries[0].FeedID)
+		// Undated items are as new as when they were seen
+		assert.WithinDuration(t, time.Now(), entries[0].PublishTime.Time, time.Minute)
+	})
+
+	t.Run("without a title selector", func(t *testing.T) {
+		_, entries, err := testFetcher.Scrape(context.Background(), srv.URL, seymour.ScrapeConfig{Item: "h2 a"})
+		require.NoError(t, err)
+
+		require.Len(t, entries, 1)
+		assert.Equal(t, "Version 2", entries[0].Title)
+		assert.Equal(t, srv.URL+"/changelog/2", entries[0].Link)
+	})
+}
+
+func TestValidateScrapeConfig(t *testing.T) {
+	assert.NoError(t, ValidateScrapeConfig(seymour.ScrapeConfig{Item: "article", Title: "h1 > a", Date: "time[datetime]"}))
+	assert.ErrorContains(t, ValidateScrapeConfig(seymour.ScrapeConfig{Title: "h1"}), "item selector is required")
+	assert.ErrorContains(t, ValidateScrapeConfig(seymour.ScrapeConfig{Item: "article", Link: "a[href"}), "invalid link selector")
+}
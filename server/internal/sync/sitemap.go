@@ -0,0 +1,302 @@
+package sync
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// SitemapSource follows a site through its sitemap, for docs sites and publications that don't
+// have a feed.
+//
+// Its urls are the sitemap's with sitemap: in front, like sitemap:https://example.com/sitemap.xml,
+// and sitemap indexes are walked to the sitemaps they list. Pages listed with a newer <lastmod> than
+// the ones already taken become entries, and a page that changes again is a new entry, since its
+// GUID is its url along with its <lastmod>. Pages without a <lastmod> can't be told apart when they
+// change, so they're only picked up while the feed has nothing else to go on.
+//
+// Pages aren't read while syncing: an entry is titled with the page's url and left for the reader
+// view to read when it's opened, which fills in its title and description then. That keeps a sync to
+// the sitemaps themselves however many pages changed. Still, only MaxPages are taken a sync, oldest
+// first, and the feed's cursor keeps track of how far it's got so the rest are taken the syncs after.
+// The first sync starts from the most recent pages rather than the oldest.
+type SitemapSource struct {
+	MaxPages int // Defaults to 50
+}
+
+const (
+	defaultSitemapPages = 50
+
+	// How many of an index's sitemaps are read a sync: the most recent on the first, since it only
+	// takes the most recent pages, and then the ones changed since. Past it, pages of the changed
+	// sitemaps that weren't read can be missed if they're older than the ones taken.
+	firstSitemaps = 5
+	maxSitemaps   = 50
+)
+
+// The gzip magic number, for sitemaps served as .xml.gz without a Content-Encoding.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// sitemapDoc is either a sitemap's <urlset> or an index's <sitemapindex>.
+type sitemapDoc struct {
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+
+	modified time.Time
+	dateOnly bool // A <lastmod> of just the day, which could be any time in it
+}
+
+// sitemapCursor is how far through the sitemap's pages a feed has got: the latest <lastmod> taken,
+// and the pages taken with it, since more can turn up with the same one.
+type sitemapCursor struct {
+	LastMod time.Time `json:"lastmod"`
+	Locs    []string  `json:"locs,omitempty"`
+}
+
+// after reports whether the page comes after the ones the cursor's been through.
+func (c sitemapCursor) after(loc sitemapLoc) bool {
+	if loc.modified.IsZero() {
+		return false
+	}
+
+	return loc.modified.After(c.LastMod) || (loc.modified.Equal(c.LastMod) && !slices.Contains(c.Locs, loc.Loc))
+}
+
+// advance moves the cursor past the page.
+func (c sitemapCursor) advance(loc sitemapLoc) sitemapCursor {
+	switch {
+	case loc.modified.IsZero() || loc.modified.Before(c.LastMod):
+		return c
+	case loc.modified.After(c.LastMod):
+		c = sitemapCursor{LastMod: loc.modified}
+	}
+	c.Locs = append(slices.Clip(c.Locs), loc.Loc)
+
+	return c
+}
+
+func (SitemapSource) Kind() seymour.FeedKind { return "sitemap" }
+
+func (s SitemapSource) Fetch(ctx context.Context, f *Fetcher, feed seymour.Feed) (seymour.Feed, []seymour.FeedEntry, error) {
+	sitemapURL, _, err := sourcePath(feed.URL)
+	if err != nil {
+		return seymour.Feed{}, nil, err
+	}
+	u, err := url.Parse(sitemapURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return seymour.Feed{}, nil, fmt.Errorf("sitemap urls look like sitemap:https://example.com/sitemap.xml, got %q", feed.URL)
+	}
+
+	var cursor sitemapCursor
+	if feed.SourceCursor != nil && *feed.SourceCursor != "" {
+		if err := json.Unmarshal([]byte(*feed.SourceCursor), &cursor); err != nil {
+			return seymour.Feed{}, nil, fmt.Errorf("error decoding sitemap cursor: %w", err)
+		}
+	}
+	first := cursor.LastMod.IsZero()
+
+	doc, err := f.sitemap(ctx, sitemapURL)
+	if err != nil {
+		return seymour.Feed{}, nil, err
+	}
+	locs := doc.URLs
+
+	var warning *string
+	if children := changedSitemaps(doc.Sitemaps, cursor); len(children) > 0 {
+		var gone int
+		for _, child := range children {
+			childDoc, err := f.sitemap(ctx, child.Loc)
+			// One that's gone has no pages to miss, anything else has to be read before moving on
+			if statusErr := (&StatusError{}); errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone) {
+				gone++
+				continue
+			}
+			if err != nil {
+				return seymour.Feed{}, nil, fmt.Errorf("error getting the index's sitemaps: %w", err)
+			}
+			// Indexes only go one level deep
+			locs = append(locs, childDoc.URLs...)
+		}
+		if gone > 0 {
+			w := fmt.Sprintf("%d of the index's sitemaps couldn't be found", gone)
+			warning = &w
+		}
+	}
+
+	// The same page can be listed by more than one sitemap
+	seen := map[string]bool{}
+	locs = slices.DeleteFunc(locs, func(loc sitemapLoc) bool {
+		if seen[loc.Loc] {
+			return true
+		}
+		seen[loc.Loc] = true
+		return false
+	})
+
+	pages := newPages(locs, cursor, first, cmp.Or(s.MaxPages, defaultSitemapPages))
+
+	var (
+		title       = u.Host
+		description = "Pages from " + sitemapURL
+		fetched     = seymour.Feed{Title: &title, Description: &description, ParseWarning: warning}
+	)
+	entries := make([]seymour.FeedEntry, 0, len(pages))
+	for _, page := range pages {
+		entries = append(entries, sitemapEntry(page))
+		cursor = cursor.advance(page)
+	}
+	if !cursor.LastMod.IsZero() {
+		encoded, err := json.Marshal(cursor)
+		if err != nil {
+			return seymour.Feed{}, nil, fmt.Errorf("error encoding sitemap cursor: %w", err)
+		}
+		next := string(encoded)
+		fetched.SourceCursor = &next
+	}
+
+	return fetched, entries, nil
+}
+
+// changedSitemaps are the index's sitemaps that could have pages past the cursor, or the most
+// recently modified ones on the first sync.
+func changedSitemaps(sitemaps []sitemapLoc, cursor sitemapCursor) []sitemapLoc {
+	changed := slices.Clone(sitemaps)
+	if cursor.LastMod.IsZero() {
+		slices.SortStableFunc(changed, func(a, b sitemapLoc) int {
+			return b.modified.Compare(a.modified)
+		})
+		return changed[:min(len(changed), firstSitemaps)]
+	}
+
+	// A sitemap without a <lastmod> could have anything in it, so those are always read
+	changed = slices.DeleteFunc(changed, func(loc sitemapLoc) bool {
+		end := loc.modified
+		if loc.dateOnly {
+			end = end.Add(24 * time.Hour)
+		}
+		return !loc.modified.IsZero() && end.Before(cursor.LastMod)
+	})
+	slices.SortStableFunc(changed, func(a, b sitemapLoc) int {
+		return a.modified.Compare(b.modified)
+	})
+	return changed[:min(len(changed), maxSitemaps)]
+}
+
+// newPages are the next pages past the cursor, at most limit of them and oldest first. The first sync
+// takes the most recent pages instead, along with any without a <lastmod>.
+func newPages(locs []sitemapLoc, cursor sitemapCursor, first bool, limit int) []sitemapLoc {
+	var pages []sitemapLoc
+	for _, loc := range locs {
+		if first || cursor.after(loc) {
+			pages = append(pages, loc)
+		}
+	}
+
+	oldestFirst := func(a, b sitemapLoc) int {
+		return cmp.Or(a.modified.Compare(b.modified), cmp.Compare(a.Loc, b.Loc))
+	}
+	if first {
+		slices.SortFunc(pages, func(a, b sitemapLoc) int { return oldestFirst(b, a) })
+		pages = pages[:min(len(pages), limit)]
+	}
+	slices.SortFunc(pages, oldestFirst)
+
+	return pages[:min(len(pages), limit)]
+}
+
+// sitemap gets the sitemap or sitemap index at the url, resolving its locations against it.
+func (f *Fetcher) sitemap(ctx context.Context, sitemapURL string) (sitemapDoc, error) {
+	page, err := f.get(ctx, sitemapURL)
+	if err != nil {
+		return sitemapDoc{}, fmt.Errorf("error getting sitemap: %w", err)
+	}
+
+	body := page.body
+	if bytes.HasPrefix(body, gzipMagic) {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return sitemapDoc{}, fmt.Errorf("error decompressing sitemap: %w", err)
+		}
+		// Held to the same limit as any other body once it's decompressed
+		body, err = io.ReadAll(io.LimitReader(zr, f.maxBodySize+1))
+		if err != nil {
+			return sitemapDoc{}, fmt.Errorf("error decompressing sitemap: %w", err)
+		}
+		if int64(len(body)) > f.maxBodySize {
+			return sitemapDoc{}, fmt.Errorf("sitemap is larger than %d bytes", f.maxBodySize)
+		}
+	}
+	body, err = toUTF8(page.contentType, body)
+	if err != nil {
+		return sitemapDoc{}, err
+	}
+
+	var doc sitemapDoc
+	if err := newXMLDecoder(body).Decode(&doc); err != nil {
+		return sitemapDoc{}, fmt.Errorf("error parsing sitemap: %w", err)
+	}
+
+	base, err := url.Parse(page.url)
+	if err != nil {
+		return sitemapDoc{}, fmt.Errorf("error parsing sitemap url: %w", err)
+	}
+	doc.URLs = resolveLocs(base, doc.URLs)
+	doc.Sitemaps = resolveLocs(base, doc.Sitemaps)
+
+	return doc, nil
+}
+
+// resolveLocs resolves the locations against the sitemap's url and parses their <lastmod>,
+// dropping any that aren't web pages.
+func resolveLocs(base *url.URL, locs []sitemapLoc) []sitemapLoc {
+	resolved := make([]sitemapLoc, 0, len(locs))
+	for _, loc := range locs {
+		u, err := base.Parse(strings.TrimSpace(loc.Loc))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		loc.Loc = u.String()
+
+		if lastMod := strings.TrimSpace(loc.LastMod); lastMod != "" {
+			loc.modified, _ = parseDate(lastMod)
+			loc.dateOnly = len(lastMod) == len(time.DateOnly)
+		}
+		resolved = append(resolved, loc)
+	}
+
+	return resolved
+}
+
+// sitemapEntry is the entry for a page of the sitemap, titled with its url until it's read.
+//
+// A page that changes again is a new entry, so its <lastmod> is part of the GUID.
+func sitemapEntry(loc sitemapLoc) seymour.FeedEntry {
+	entry := seymour.FeedEntry{
+		GUID:        loc.Loc,
+		Title:       loc.Loc,
+		Link:        loc.Loc,
+		PublishTime: seymour.DBTime{Time: loc.modified},
+	}
+	if !loc.modified.IsZero() {
+		entry.GUID = loc.Loc + "#" + loc.modified.UTC().Format(time.RFC3339)
+	}
+
+	return entry
+}
@@ -0,0 +1,137 @@
+package sync
+
+import (
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+const testSitemapIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>/posts.xml.gz</loc><lastmod>2024-03-05</lastmod></sitemap>
+  <sitemap><loc>/archive.xml</loc><lastmod>2023-01-01</lastmod></sitemap>
+</sitemapindex>`
+
+const testSitemapPosts = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>/docs/older</loc><lastmod>2024-03-01</lastmod></url>
+  <url><loc>/docs/newest</loc><lastmod>2024-03-05T10:00:00Z</lastmod></url>
+  <url><loc>/docs/missing</loc><lastmod>2024-03-03</lastmod></url>
+  <url><loc>/docs/daily</loc><lastmod>2024-03-04</lastmod></url>
+</urlset>`
+
+const testSitemapArchive = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>/docs/newest</loc><lastmod>2024-03-05T10:00:00Z</lastmod></url>
+  <url><loc>/docs/ancient</loc><lastmod>2023-01-01</lastmod></url>
+</urlset>`
+
+func TestSitemapSource(t *testing.T) {
+	var (
+		mu   gosync.Mutex
+		hits = map[string]int{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(testSitemapIndex))
+		case "/posts.xml.gz":
+			// Compressed as a file, not as a Content-Encoding
+			w.Header().Set("Content-Type", "application/gzip")
+			zw := gzip.NewWriter(w)
+			_, _ = zw.Write([]byte(testSitemapPosts))
+			_ = zw.Close()
+		case "/archive.xml":
+			_, _ = w.Write([]byte(testSitemapArchive))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	f, err := NewFetcher(FetcherOptions{Sources: []Source{SitemapSource{MaxPages: 3}}})
+	require.NoError(t, err)
+
+	feed := seymour.Feed{ID: "feed-sitemap", URL: "sitemap:" + srv.URL + "/sitemap.xml", Kind: "sitemap"}
+	synced, entries, err := f.Feed(context.Background(), feed)
+	require.NoError(t, err)
+
+	u, _ := url.Parse(srv.URL)
+	assert.Equal(t, u.Host, *synced.Title)
+	assert.Nil(t, synced.ParseWarning)
+
+	// The first sync takes the most recently modified, up to the limit, oldest of them first
+	require.Len(t, entries, 3)
+	assert.Equal(t, srv.URL+"/docs/missing", entries[0].Link)
+	assert.Equal(t, srv.URL+"/docs/daily", entries[1].Link)
+	assert.Equal(t, srv.URL+"/docs/newest", entries[2].Link)
+	assert.Equal(t, srv.URL+"/docs/newest#2024-03-05T10:00:00Z", entries[2].GUID)
+	// Titled with the url until the reader view reads the page
+	assert.Equal(t, srv.URL+"/docs/newest", entries[2].Title)
+	assert.Empty(t, entries[2].Description)
+	assert.Equal(t, time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC), entries[2].PublishTime.Time)
+	assert.Empty(t, entries[2].Content)
+	assert.Equal(t, "feed-sitemap", entries[2].FeedID)
+	assert.Equal(t, 1, hits["/archive.xml"])
+	// None of the pages themselves are fetched
+	for path := range hits {
+		assert.False(t, strings.HasPrefix(path, "/docs/"), path)
+	}
+
+	t.Run("picks up where it left off", func(t *testing.T) {
+		feed := feed
+		feed.SourceCursor = synced.SourceCursor
+		_, entries, err := f.Feed(context.Background(), feed)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+		// The archive hasn't changed since, so it's not read again
+		assert.Equal(t, 1, hits["/archive.xml"])
+	})
+
+	t.Run("takes what's past the limit next sync", func(t *testing.T) {
+		feed := feed
+		feed.SourceCursor = new(string)
+		*feed.SourceCursor = `{"lastmod": "2024-02-01T00:00:00Z"}`
+
+		synced, entries, err := f.Feed(context.Background(), feed)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		assert.Equal(t, srv.URL+"/docs/older", entries[0].Link)
+		assert.Equal(t, srv.URL+"/docs/missing", entries[1].Link)
+		assert.Equal(t, srv.URL+"/docs/daily", entries[2].Link)
+
+		feed.SourceCursor = synced.SourceCursor
+		_, entries, err = f.Feed(context.Background(), feed)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, srv.URL+"/docs/newest", entries[0].Link)
+	})
+
+	t.Run("urls kept as they are", func(t *testing.T) {
+		base, _ := url.Parse("https://example.com/sitemap.xml")
+		locs := resolveLocs(base, []sitemapLoc{{Loc: " /search?id=1&section=2&copy=3 \n", LastMod: " 2024-03-01 "}})
+		require.Len(t, locs, 1)
+		assert.Equal(t, "https://example.com/search?id=1&section=2&copy=3", locs[0].Loc)
+		assert.True(t, locs[0].dateOnly)
+	})
+
+	t.Run("invalid url", func(t *testing.T) {
+		_, _, err := f.Feed(context.Background(), seymour.Feed{URL: "sitemap:example.com", Kind: "sitemap"})
+		assert.ErrorContains(t, err, "sitemap urls look like")
+	})
+}
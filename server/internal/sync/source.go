@@ -0,0 +1,173 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// Source is somewhere entries come from that isn't an RSS, Atom, or JSON feed, like a site's API.
+//
+// Feeds from a source are stored with its kind, and their urls start with it as the scheme,
+// e.g. github:owner/repo. The entries it produces are judged and shown like any other feed's.
+type Source interface {
+	Kind() seymour.FeedKind
+
+	// Fetch gets the feed's details and latest entries, the same as [Fetcher.Feed] does for actual feeds.
+	//
+	// Requests should go through the fetcher, e.g. with [Fetcher.GetJSON], so they're guarded and
+	// carry the feed's credentials. Entries without a publish time are given the time they were seen.
+	// A source that needs to pick up where it left off returns a SourceCursor with the feed, which it's
+	// handed back with the feed once its entries are stored.
+	Fetch(ctx context.Context, f *Fetcher, feed seymour.Feed) (seymour.Feed, []seymour.FeedEntry, error)
+}
+
+// DefaultSources are the sources a [Fetcher] knows about when it isn't given any.
+func DefaultSources() []Source {
+	return []Source{GitHubSource{}, HackerNewsSource{}, MastodonSource{}, ScrapeSource{}, SitemapSource{}, WatchSource{}}
+}
+
+// Kind works out what kind of feed the url is for from its scheme, [seymour.FeedKindFeed] unless
+// it's one of the fetcher's sources.
+func (f *Fetcher) Kind(rawURL string) seymour.FeedKind {
+	scheme, _, ok := strings.Cut(rawURL, ":")
+	if !ok {
+		return seymour.FeedKindFeed
+	}
+	if _, ok := f.sources[seymour.FeedKind(strings.ToLower(scheme))]; ok {
+		return seymour.FeedKind(strings.ToLower(scheme))
+	}
+
+	return seymour.FeedKindFeed
+}
+
+// fromSource fetches the feed from its source, scheduling it like any other feed.
+func (f *Fetcher) fromSource(ctx context.Context, feed seymour.Feed) (seymour.Feed, []seymour.FeedEntry, error) {
+	src, ok := f.sources[feed.Kind]
+	if !ok {
+		return seymour.Feed{}, nil, fmt.Errorf("unknown feed kind: %s", feed.Kind)
+	}
+
+	fetched, entries, err := src.Fetch(ctx, f, feed)
+	if err != nil {
+		return seymour.Feed{}, nil, err
+	}
+
+	var (
+		now   = time.Now()
+		sched = &schedule{}
+	)
+	for i := range entries {
+		entries[i].FeedID = feed.ID
+		if entries[i].PublishTime.Time.IsZero() {
+			entries[i].PublishTime.Time = now
+		} else {
+			sched.published = append(sched.published, entries[i].PublishTime.Time)
+		}
+	}
+
+	fetched.ID = feed.ID
+	if fetched.Title == nil {
+		fetched.Title = new(string)
+	}
+	if fetched.Description == nil {
+		fetched.Description = new(string)
+	}
+	interval := sched.interval(now)
+	fetched.SyncInterval = int64(interval / time.Second)
+	fetched.NextSyncAt = &seymour.DBTime{Time: sched.next(now, interval)}
+
+	return fetched, entries, nil
+}
+
+// GetJSON makes the request, for a [Source], and decodes the JSON response into v.
+//
+// APIs that say they're rate limiting give a [RetryAfterError], whether with a Retry-After
+// or an X-RateLimit-Reset header.
+func (f *Fetcher) GetJSON(req *http.Request, v any) error {
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	resp, body, err := f.do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	if until, ok := rateLimited(resp, time.Now()); ok {
+		return &RetryAfterError{StatusCode: resp.StatusCode, Until: until}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return nil
+}
+
+// rateLimited checks if the response is a server saying it's had too many requests, and until when.
+//
+// A 429 or 503 is always taken as one, to be left alone for a while if it doesn't say how long.
+func rateLimited(resp *http.Response, now time.Time) (time.Time, bool) {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+	case http.StatusForbidden:
+		// GitHub's way of saying it, but only when there's nothing left or it says when to come back
+		if resp.Header.Get("X-RateLimit-Remaining") != "0" && resp.Header.Get("Retry-After") == "" {
+			return time.Time{}, false
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	if until, ok := retryAfter(resp.Header, now); ok && until.After(now) {
+		return until, true
+	}
+
+	// Unix seconds from GitHub, a timestamp from Mastodon
+	reset := strings.TrimSpace(resp.Header.Get("X-RateLimit-Reset"))
+	var until time.Time
+	if secs, err := strconv.ParseInt(reset, 10, 64); err == nil {
+		until = time.Unix(secs, 0)
+	} else if t, ok := parseDate(reset); ok {
+		until = t
+	}
+	if !until.After(now) {
+		// Still a sign to back off, or the feeds of a host that's struggling would all keep failing
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			return now.Add(defaultRetryAfter).UTC(), true
+		}
+		return time.Time{}, false
+	}
+	if limit := now.Add(maxRetryAfter); until.After(limit) {
+		until = limit
+	}
+
+	return until.UTC(), true
+}
+
+// sourcePath is what's after the scheme of a source's url, e.g. owner/repo for github:owner/repo,
+// along with its query.
+func sourcePath(rawURL string) (string, url.Values, error) {
+	_, rest, _ := strings.Cut(rawURL, ":")
+	rest, rawQuery, _ := strings.Cut(rest, "?")
+	rest, err := url.PathUnescape(rest)
+	if err != nil {
+		return "", nil, fmt.Errorf("error parsing url: %w", err)
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", nil, fmt.Errorf("error parsing url query: %w", err)
+	}
+
+	return strings.TrimSpace(rest), query, nil
+}
@@ -0,0 +1,60 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+func TestSources(t *testing.T) {
+	f, err := NewFetcher(FetcherOptions{Sources: []Source{
+		GitHubSource{},
+		HackerNewsSource{},
+		MastodonSource{},
+	}})
+	require.NoError(t, err)
+
+	t.Run("kinds", func(t *testing.T) {
+		assert.Equal(t, seymour.FeedKind("github"), f.Kind("github:golang/go"))
+		assert.Equal(t, seymour.FeedKind("hn"), f.Kind("HN:golang"))
+		assert.Equal(t, seymour.FeedKind("mastodon"), f.Kind("mastodon:@gopher@example.social"))
+		assert.Equal(t, seymour.FeedKindFeed, f.Kind("https://example.com/feed.xml"))
+		assert.Equal(t, seymour.FeedKindFeed, f.Kind("gopher:example.com"))
+
+		// Nothing to discover
+		candidates, err := f.Discover(context.Background(), "github:golang/go")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"github:golang/go"}, candidates)
+	})
+
+	t.Run("unknown kind", func(t *testing.T) {
+		_, _, err := f.Feed(context.Background(), seymour.Feed{ID: "feed-x", URL: "gopher:example.com", Kind: "gopher"})
+		assert.ErrorContains(t, err, "unknown feed kind")
+	})
+}
+
+func TestSources_RateLimited(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprint(reset.Unix()))
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	f, err := NewFetcher(FetcherOptions{Sources: []Source{GitHubSource{APIURL: srv.URL}}})
+	require.NoError(t, err)
+
+	_, _, err = f.Feed(context.Background(), seymour.Feed{ID: "feed-gh", URL: "github:golang/go", Kind: "github"})
+	var retryErr *RetryAfterError
+	require.ErrorAs(t, err, &retryErr)
+	assert.Equal(t, reset.UTC(), retryErr.Until)
+}
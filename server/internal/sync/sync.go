@@ -2,14 +2,18 @@ package sync
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
-	"io"
+	"mime"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/sym01/htmlsanitizer"
 
@@ -20,93 +24,303 @@ import (
 type rssFeedResp struct {
 	XMLName xml.Name `xml:"rss"`
 	Channel []struct {
-		Title       string `xml:"title"`
-		Description string `xml:"description"`
-		Link        string `xml:"link"`
-		Items       []struct {
+		Title       string   `xml:"title"`
+		Description string   `xml:"description"`
+		TTL         string   `xml:"ttl"` // In minutes
+		SkipHours   []string `xml:"skipHours>hour"`
+		SkipDays    []string `xml:"skipDays>day"`
+		Generator   string   `xml:"generator"`
+		// Both the channel's <link> and any <atom:link>s, which is where WebSub's hub and self are
+		Links []feedLink `xml:"link"`
+		Items []struct {
 			Title       string   `xml:"title"`
 			Links       []string `xml:"link"`
 			GUID        string   `xml:"guid"`
 			Description string   `xml:"description"`
 			PubDate     string   `xml:"pubDate"`
+			Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+			Encoded     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+			Author      string   `xml:"author"`
+			Creators    []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+			Categories  []string `xml:"category"`
+			itemMedia
 		} `xml:"item"`
 	} `xml:"channel"`
 }
 
 // Represents a response from an Atom feed fetch.
 type atomFeedResp struct {
-	XMLName  xml.Name `xml:"feed"`
-	Title    string   `xml:"title"`
-	Subtitle string   `xml:"subtitle"`
-	Links    []struct {
-		Href string `xml:"href,attr"`
-		Rel  string `xml:"rel,attr"`
-	} `xml:"link"`
-	Entries []struct {
+	XMLName   xml.Name     `xml:"feed"`
+	Title     string       `xml:"title"`
+	Subtitle  string       `xml:"subtitle"`
+	Authors   []atomPerson `xml:"author"`
+	Links     []feedLink   `xml:"link"`
+	Generator string       `xml:"generator"`
+	Entries   []struct {
 		Title string `xml:"title"`
 		ID    string `xml:"id"`
 		Links []struct {
-			Href string `xml:"href,attr"`
-			Rel  string `xml:"rel,attr"`
+			Href   string `xml:"href,attr"`
+			Rel    string `xml:"rel,attr"`
+			Type   string `xml:"type,attr"`
+			Length string `xml:"length,attr"`
 		} `xml:"link"`
-		Summary string `xml:"summary"`
-		Content string `xml:"content"`
-		Updated string `xml:"updated"`
+		Summary    string         `xml:"summary"`
+		Content    atomContent    `xml:"content"`
+		Published  string         `xml:"published"`
+		Updated    string         `xml:"updated"`
+		Authors    []atomPerson   `xml:"author"`
+		Categories []atomCategory `xml:"category"`
+		itemMedia
 	} `xml:"entry"`
 }
 
-var syncClient = &http.Client{
-	Timeout: time.Second * 3,
+// A feed level Atom link, which is where WebSub hubs are advertised.
+type feedLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
 }
 
-func Feed(ctx context.Context, feedID, feedURL string) (seymour.Feed, []seymour.FeedEntry, error) {
-	resp, err := syncClient.Get(feedURL)
+// An Atom person construct, used for authors.
+type atomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+// An Atom category: the term is required, the label is the human readable version.
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+// The content of an Atom entry: either text, escaped html, or inline xhtml markup.
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// HTML returns the content as markup regardless of how it was embedded.
+func (c atomContent) HTML() string {
+	if c.Type == "xhtml" {
+		return c.Inner
+	}
+
+	return c.Text
+}
+
+// ErrNotModified is returned by [Feed] when the server reports that the feed
+// hasn't changed since the validators stored on the feed were issued.
+var ErrNotModified = errors.New("feed not modified")
+
+// Feed fetches and parses the feed at feed.URL, or gets it from its [Source] if it isn't an actual feed.
+//
+// If the feed carries an ETag or Last-Modified from a previous fetch, the request
+// is made conditional and [ErrNotModified] is returned when the server answers 304.
+//
+// The returned feed is scheduled for its next sync based on how often it posts and what
+// it and its server ask for. A server that asks to be retried later gets a [RetryAfterError].
+//
+// If the feed was permanently redirected, the returned feed's URL is where it moved to,
+// otherwise it's empty. That's the case for [ErrNotModified] too, where only it and the next sync are set.
+func (f *Fetcher) Feed(ctx context.Context, feed seymour.Feed) (seymour.Feed, []seymour.FeedEntry, error) {
+	if feed.Kind != "" && feed.Kind != seymour.FeedKindFeed {
+		return f.fromSource(ctx, feed)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed.URL, nil)
+	if err != nil {
+		return seymour.Feed{}, nil, fmt.Errorf("error creating feed request: %w", err)
+	}
+	if feed.ETag != nil && *feed.ETag != "" {
+		req.Header.Set("If-None-Match", *feed.ETag)
+	}
+	if feed.LastModified != nil && *feed.LastModified != "" {
+		req.Header.Set("If-Modified-Since", *feed.LastModified)
+	}
+
+	resp, body, err := f.do(req)
 	if err != nil {
 		return seymour.Feed{}, nil, fmt.Errorf("error getting feed url: %w", err)
 	}
-	defer func() { _ = resp.Body.Close() }()
 
+	movedTo := withoutCredentials(permanentURL(resp), f.creds)
+	if resp.StatusCode == http.StatusNotModified {
+		next := Reschedule(feed, maxAge(resp.Header), time.Now())
+		return seymour.Feed{ID: feed.ID, URL: movedTo, NextSyncAt: &next}, nil, ErrNotModified
+	}
+	if until, ok := rateLimited(resp, time.Now()); ok {
+		return seymour.Feed{}, nil, &RetryAfterError{StatusCode: resp.StatusCode, Until: until}
+	}
 	if resp.StatusCode != http.StatusOK {
-		return seymour.Feed{}, nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return seymour.Feed{}, nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	sched := &schedule{maxAge: maxAge(resp.Header)}
+	parsed, entries, err := parse(feed.ID, resp.Header.Get("Content-Type"), body, sched, &paging{})
+	if err != nil {
+		return seymour.Feed{}, nil, err
+	}
+
+	now := time.Now()
+	interval := sched.interval(now)
+	parsed.SyncInterval = int64(interval / time.Second)
+	parsed.NextSyncAt = &seymour.DBTime{Time: sched.next(now, interval)}
+	// Kept for the syncs that find it unchanged
+	skipHours, skipDays := sched.skips()
+	parsed.SkipHours, parsed.SkipDays = &skipHours, &skipDays
+
+	parsed.URL = movedTo
+
+	// Hubs advertised in the headers win over the ones in the document
+	if hub, topic := webSubHeaderLinks(resp.Header); hub != "" {
+		parsed.HubURL, parsed.TopicURL = &hub, &topic
+	}
+	if parsed.HubURL != nil && *parsed.HubURL != "" && (parsed.TopicURL == nil || *parsed.TopicURL == "") {
+		topic := cmp.Or(movedTo, feed.URL)
+		parsed.TopicURL = &topic
+	}
+
+	// Remember the validators for the next conditional request
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		parsed.ETag = &etag
+	}
+	if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+		parsed.LastModified = &lastModified
+	}
+
+	return parsed, entries, nil
+}
+
+// permanentURL works out where the response's url moved to for good, or empty if it didn't.
+//
+// Only an unbroken run of 301s and 308s from the original request counts, since anything
+// after a temporary redirect could change back.
+func permanentURL(resp *http.Response) string {
+	// Walk back from the final request to the original one: each redirected
+	// request carries the response that sent the client to it
+	hops := []*http.Request{}
+	for req := resp.Request; req != nil; {
+		hops = append(hops, req)
+		if req.Response == nil {
+			break
+		}
+		req = req.Response.Request
+	}
+	slices.Reverse(hops)
+
+	var movedTo string
+	for _, req := range hops[1:] {
+		if code := req.Response.StatusCode; code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+			break
+		}
+		movedTo = req.URL.String()
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	return movedTo
+}
+
+// parse detects the format of the feed document and parses it accordingly.
+//
+// Any publish dates that couldn't be parsed are noted in the feed's parse warning, and
+// any hints about how often to fetch the feed are collected in sched, and where its older
+// entries are in pages.
+func parse(feedID, contentType string, body []byte, sched *schedule, pages *paging) (seymour.Feed, []seymour.FeedEntry, error) {
+	body, err := toUTF8(contentType, body)
 	if err != nil {
-		return seymour.Feed{}, nil, fmt.Errorf("error reading response body: %w", err)
+		return seymour.Feed{}, nil, err
 	}
 
-	// Detect feed format based on the root XML element
-	format := detectFormat(body)
-	switch format {
+	var (
+		fetchedAt = time.Now()
+		dates     = &dateParser{}
+		feed      seymour.Feed
+		entries   []seymour.FeedEntry
+	)
+	switch detectFormat(contentType, body) {
+	case "json":
+		feed, entries, err = parseJSONFeed(feedID, body, dates, pages)
 	case "atom":
-		return parseAtom(feedID, body)
+		feed, entries, err = parseAtom(feedID, body, dates, pages)
+	case "rdf":
+		feed, entries, err = parseRDF(feedID, body, dates)
 	default:
-		return parseRSS(feedID, body)
+		feed, entries, err = parseRSS(feedID, body, dates, sched, pages)
+	}
+	if err != nil {
+		return seymour.Feed{}, nil, err
 	}
+
+	for i := range entries {
+		// Items without a guid get a stable one from what they do have
+		entries[i].GUID = strings.TrimSpace(entries[i].GUID)
+		if entries[i].GUID == "" {
+			entries[i].GUID = synthesizeGUID(entries[i])
+		}
+
+		// Without a date from the feed, the best we know is when it was seen
+		if entries[i].PublishTime.Time.IsZero() {
+			entries[i].PublishTime.Time = fetchedAt
+		} else {
+			sched.published = append(sched.published, entries[i].PublishTime.Time)
+		}
+	}
+
+	feed.ParseWarning = dates.warning()
+	return feed, entries, nil
 }
 
-// detectFormat peeks at the root XML element to determine if the feed is RSS or Atom.
-func detectFormat(data []byte) string {
-	decoder := xml.NewDecoder(bytes.NewReader(data))
+// synthesizeGUID builds an identity for an entry that doesn't have one from its
+// link, title, and the publish date given by the feed (if any).
+//
+// This has to stay in line with the backfill in the migration that scoped guids to feeds.
+func synthesizeGUID(entry seymour.FeedEntry) string {
+	var date string
+	if !entry.PublishTime.Time.IsZero() {
+		date = entry.PublishTime.Time.Format(time.RFC3339)
+	}
+
+	return strings.Join([]string{entry.Link, entry.Title, date}, "|")
+}
+
+// detectFormat determines if the feed is a JSON Feed, RSS, RDF or Atom.
+//
+// JSON Feeds are recognized by their content type or a leading '{', otherwise
+// it peeks at the root XML element.
+func detectFormat(contentType string, data []byte) string {
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/feed+json" || mediaType == "application/json" {
+		return "json"
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return "json"
+	}
+
+	decoder := newXMLDecoder(data)
 	for {
 		tok, err := decoder.Token()
 		if err != nil {
 			return "rss"
 		}
 		if se, ok := tok.(xml.StartElement); ok {
-			if se.Name.Local == "feed" {
+			switch se.Name.Local {
+			case "feed":
 				return "atom"
+			case "RDF":
+				return "rdf"
 			}
 			return "rss"
 		}
 	}
 }
 
-func parseRSS(feedID string, data []byte) (seymour.Feed, []seymour.FeedEntry, error) {
+func parseRSS(feedID string, data []byte, dates *dateParser, sched *schedule, pages *paging) (seymour.Feed, []seymour.FeedEntry, error) {
 	var feedResp rssFeedResp
-	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&feedResp); err != nil {
+	if err := newXMLDecoder(data).Decode(&feedResp); err != nil {
 		return seymour.Feed{}, nil, fmt.Errorf("error decoding rss feed: %w", err)
 	}
+	if len(feedResp.Channel) == 0 {
+		return seymour.Feed{}, nil, errors.New("rss feed has no channel")
+	}
 
 	entries := []seymour.FeedEntry{}
 	for _, channel := range feedResp.Channel {
@@ -121,83 +335,131 @@ func parseRSS(feedID string, data []byte) (seymour.Feed, []seymour.FeedEntry, er
 				nonEmptyLink = link
 			}
 
-			// Parse the publish date
-			var publishedAt seymour.DBTime
-			if parsedTime, err := time.Parse(time.RFC1123, item.PubDate); err == nil {
-				publishedAt.Time = parsedTime
-			}
-			if parsedTime, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
-				publishedAt.Time = parsedTime
-			}
+			// Parse the publish date, some feeds use Dublin Core's instead
+			publishedAt := dates.parse(item.PubDate, item.Date)
 
 			entries = append(entries, seymour.FeedEntry{
 				FeedID:      feedID,
 				GUID:        item.GUID,
 				Title:       sanitize(item.Title),
 				Description: sanitize(item.Description),
+				Content:     sanitizeHTML(item.Encoded),
 				Link:        nonEmptyLink,
 				PublishTime: publishedAt,
+				Media:       item.media(),
+				Authors:     uniqueNonEmpty(append([]string{authorName(item.Author)}, item.Creators...)),
+				Categories:  uniqueNonEmpty(item.Categories),
 			})
 		}
 	}
 
+	channel := feedResp.Channel[0]
+	sched.ttl = rssTTL(channel.TTL)
+	sched.skipHours = skipHours(channel.SkipHours)
+	sched.skipDays = skipDays(channel.SkipDays)
+	hub, topic := webSubLinks(channel.Links)
+	pagingLinks(channel.Links, pages)
+	pages.wordPress = isWordPress(channel.Generator)
+
 	// Only the fields being updated:
 	return seymour.Feed{
 		ID:          feedID,
-		Title:       &feedResp.Channel[0].Title,
-		Description: &feedResp.Channel[0].Description,
+		Title:       &channel.Title,
+		Description: &channel.Description,
+		HubURL:      &hub,
+		TopicURL:    &topic,
 	}, entries, nil
 }
 
-func parseAtom(feedID string, data []byte) (seymour.Feed, []seymour.FeedEntry, error) {
+func parseAtom(feedID string, data []byte, dates *dateParser, pages *paging) (seymour.Feed, []seymour.FeedEntry, error) {
 	var feedResp atomFeedResp
-	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&feedResp); err != nil {
+	if err := newXMLDecoder(data).Decode(&feedResp); err != nil {
 		return seymour.Feed{}, nil, fmt.Errorf("error decoding atom feed: %w", err)
 	}
 
 	entries := []seymour.FeedEntry{}
 	for _, entry := range feedResp.Entries {
-		// Find the best link: prefer "alternate", fall back to first with href
-		var link string
+		// Find the best link: prefer "alternate", fall back to first with href.
+		// Enclosure links are attachments rather than the post itself.
+		var (
+			link       string
+			enclosures []rssEnclosure
+		)
 		for _, l := range entry.Links {
 			if l.Href == "" {
 				continue
 			}
+			if l.Rel == "enclosure" {
+				enclosures = append(enclosures, rssEnclosure{URL: l.Href, Type: l.Type, Length: l.Length})
+				continue
+			}
 			if link == "" || l.Rel == "alternate" {
 				link = l.Href
 			}
 		}
 
 		// Use content if summary is empty
+		content := entry.Content.HTML()
 		description := entry.Summary
 		if description == "" {
-			description = entry.Content
+			description = content
 		}
 
-		// Parse the publish date (Atom uses RFC3339)
-		var publishedAt seymour.DBTime
-		if parsedTime, err := time.Parse(time.RFC3339, entry.Updated); err == nil {
-			publishedAt.Time = parsedTime
+		// Entries inherit the feed's authors when they don't have their own
+		people := entry.Authors
+		if len(people) == 0 {
+			people = feedResp.Authors
+		}
+		var authors []string
+		for _, p := range people {
+			if p.Name == "" {
+				p.Name = p.Email
+			}
+			authors = append(authors, p.Name)
 		}
 
+		var categories []string
+		for _, c := range entry.Categories {
+			if c.Label == "" {
+				c.Label = c.Term
+			}
+			categories = append(categories, c.Label)
+		}
+
+		// Parse the publish date, preferring when it was first published
+		publishedAt := dates.parse(entry.Published, entry.Updated)
+
 		entries = append(entries, seymour.FeedEntry{
 			FeedID:      feedID,
 			GUID:        entry.ID,
 			Title:       sanitize(entry.Title),
 			Description: sanitize(description),
+			Content:     sanitizeHTML(content),
 			Link:        link,
 			PublishTime: publishedAt,
+			Media:       entry.media(enclosures...),
+			Authors:     uniqueNonEmpty(authors),
+			Categories:  uniqueNonEmpty(categories),
 		})
 	}
 
 	subtitle := feedResp.Subtitle
+	hub, topic := webSubLinks(feedResp.Links)
+	pagingLinks(feedResp.Links, pages)
+	pages.wordPress = isWordPress(feedResp.Generator)
+
 	return seymour.Feed{
 		ID:          feedID,
 		Title:       &feedResp.Title,
 		Description: &subtitle,
+		HubURL:      &hub,
+		TopicURL:    &topic,
 	}, entries, nil
 }
 
+// The longest a description is allowed to be, in bytes.
+const maxDescriptionLen = 2048
+
 // Removes all html tags from the string, usually a description.
 //
 // Also limits the length of the string so there's not a massive chunk of text being output.
@@ -219,9 +481,53 @@ func sanitize(s string) string {
 	s = strings.ReplaceAll(s, "\n", " ")
 
 	// Keep the length under 2048: some feeds put the whole post in there.
-	if len(s) > 2048 {
-		s = s[:2048]
+	// Back up to a rune boundary so a multi-byte character isn't split.
+	if len(s) > maxDescriptionLen {
+		cut := maxDescriptionLen
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		s = s[:cut]
 	}
 
 	return s
 }
+
+// Removes any unsafe HTML from the string, usually the full content of an entry,
+// while keeping the formatting tags intact.
+func sanitizeHTML(s string) string {
+	s, err := htmlsanitizer.NewHTMLSanitizer().SanitizeString(s)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(s)
+}
+
+// authorName pulls the name out of an RSS author, which is usually
+// formatted as an email address followed by the name in parentheses.
+func authorName(author string) string {
+	author = strings.TrimSpace(author)
+	if start, end := strings.Index(author, "("), strings.LastIndex(author, ")"); start >= 0 && end > start {
+		if name := strings.TrimSpace(author[start+1 : end]); name != "" {
+			return name
+		}
+	}
+
+	return author
+}
+
+// uniqueNonEmpty trims the strings and removes blanks and duplicates, keeping the original order.
+func uniqueNonEmpty(ss []string) []string {
+	ret := []string{}
+	for _, s := range ss {
+		s = html.UnescapeString(strings.TrimSpace(s))
+		if s == "" || slices.Contains(ret, s) {
+			continue
+		}
+
+		ret = append(ret, s)
+	}
+
+	return ret
+}
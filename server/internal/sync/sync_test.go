@@ -1,13 +1,33 @@
 package sync
 
 import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
+	"github.com/andybalholm/brotli"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/jdholdren/seymour/internal/seymour"
 )
 
 const testRSSFeed = `<?xml version="1.0" encoding="UTF-8"?>
@@ -54,6 +74,15 @@ const testAtomFeed = `<?xml version="1.0" encoding="UTF-8"?>
   </entry>
 </feed>`
 
+// Fetches from the test servers with the default options.
+var testFetcher = func() *Fetcher {
+	f, err := NewFetcher(FetcherOptions{})
+	if err != nil {
+		panic(err)
+	}
+	return f
+}()
+
 func TestFeed_RSS(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/rss+xml")
@@ -61,7 +90,7 @@ func TestFeed_RSS(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	feed, entries, err := Feed(context.Background(), "feed-123", srv.URL)
+	feed, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-123", URL: srv.URL})
 	require.NoError(t, err)
 
 	assert.Equal(t, "feed-123", feed.ID)
@@ -88,7 +117,7 @@ func TestFeed_Atom(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	feed, entries, err := Feed(context.Background(), "feed-456", srv.URL)
+	feed, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-456", URL: srv.URL})
 	require.NoError(t, err)
 
 	assert.Equal(t, "feed-456", feed.ID)
@@ -112,11 +141,603 @@ func TestFeed_Atom(t *testing.T) {
 	assert.Equal(t, "Second Atom post content body", entries[1].Description)
 }
 
-func TestDetectFormat(t *testing.T) {
+const testJSONFeed = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Test JSON Feed",
+  "description": "A test JSON feed",
+  "home_page_url": "https://example.com",
+  "items": [
+    {
+      "id": "json-id-1",
+      "url": "https://example.com/json-1",
+      "title": "JSON Post One",
+      "summary": "First JSON post summary",
+      "content_html": "<p>First JSON post content</p>",
+      "date_published": "2024-01-01T12:00:00Z"
+    },
+    {
+      "id": "json-id-2",
+      "url": "https://example.com/json-2",
+      "title": "JSON Post Two",
+      "content_html": "<p>Second JSON post content</p>"
+    }
+  ]
+}`
+
+func TestFeed_JSONFeed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/feed+json")
+		_, _ = w.Write([]byte(testJSONFeed))
+	}))
+	defer srv.Close()
+
+	feed, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-789", URL: srv.URL})
+	require.NoError(t, err)
+
+	assert.Equal(t, "feed-789", feed.ID)
+	assert.Equal(t, "Test JSON Feed", *feed.Title)
+	assert.Equal(t, "A test JSON feed", *feed.Description)
+
+	require.Len(t, entries, 2)
+
+	// First item has a summary
+	assert.Equal(t, "JSON Post One", entries[0].Title)
+	assert.Equal(t, "json-id-1", entries[0].GUID)
+	assert.Equal(t, "https://example.com/json-1", entries[0].Link)
+	assert.Equal(t, "First JSON post summary", entries[0].Description)
+	assert.Equal(t, "feed-789", entries[0].FeedID)
+	assert.False(t, entries[0].PublishTime.Time.IsZero())
+
+	// Second item falls back to the html content, and the fetch time for its date
+	assert.Equal(t, "JSON Post Two", entries[1].Title)
+	assert.Equal(t, "Second JSON post content", entries[1].Description)
+	assert.WithinDuration(t, time.Now(), entries[1].PublishTime.Time, time.Minute)
+	assert.Nil(t, feed.ParseWarning)
+}
+
+func TestFeed_JSONFeedNumericIDs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/feed+json")
+		_, _ = w.Write([]byte(`{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Numbered",
+  "items": [
+    {"id": 123, "url": "https://example.com/123", "title": "A number"},
+    {"id": 1.5e3, "url": "https://example.com/1500", "title": "Written oddly"},
+    {"id": "abc", "url": "https://example.com/abc", "title": "A string"}
+  ]
+}`))
+	}))
+	defer srv.Close()
+
+	_, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-numbers", URL: srv.URL})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	// Taken as they're written
+	assert.Equal(t, "123", entries[0].GUID)
+	assert.Equal(t, "1.5e3", entries[1].GUID)
+	assert.Equal(t, "abc", entries[2].GUID)
+}
+
+const testRDFFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rdf:RDF
+  xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+  xmlns:dc="http://purl.org/dc/elements/1.1/"
+  xmlns="http://purl.org/rss/1.0/">
+  <channel rdf:about="https://example.com/">
+    <title>Test RDF Feed</title>
+    <link>https://example.com/</link>
+    <description>A test RDF feed</description>
+  </channel>
+  <item rdf:about="https://example.com/rdf-1">
+    <title>RDF Post One</title>
+    <link>https://example.com/rdf-1</link>
+    <description>First RDF post description</description>
+    <dc:date>2024-01-01T12:00:00Z</dc:date>
+    <dc:creator>Jane Doe</dc:creator>
+  </item>
+  <item rdf:about="https://example.com/rdf-2">
+    <title>RDF Post Two</title>
+    <link>https://example.com/rdf-2</link>
+    <description>Second RDF post description</description>
+    <dc:date>2024-01-02</dc:date>
+  </item>
+</rdf:RDF>`
+
+func TestFeed_RDF(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rdf+xml")
+		_, _ = w.Write([]byte(testRDFFeed))
+	}))
+	defer srv.Close()
+
+	feed, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-rdf", URL: srv.URL})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Test RDF Feed", *feed.Title)
+	assert.Equal(t, "A test RDF feed", *feed.Description)
+
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "RDF Post One", entries[0].Title)
+	assert.Equal(t, "https://example.com/rdf-1", entries[0].GUID)
+	assert.Equal(t, "https://example.com/rdf-1", entries[0].Link)
+	assert.Equal(t, "First RDF post description", entries[0].Description)
+	assert.Equal(t, "feed-rdf", entries[0].FeedID)
+	assert.False(t, entries[0].PublishTime.Time.IsZero())
+
+	// Date-only dc:date values are still understood
+	assert.Equal(t, "RDF Post Two", entries[1].Title)
+	assert.False(t, entries[1].PublishTime.Time.IsZero())
+}
+
+func TestFeed_RSSWithoutChannel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"></rss>`))
+	}))
+	defer srv.Close()
+
+	_, _, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-empty", URL: srv.URL})
+	assert.Error(t, err)
+}
+
+func TestFeed_ConditionalGet(t *testing.T) {
+	const (
+		etag         = `"abc123"`
+		lastModified = "Mon, 01 Jan 2024 12:00:00 GMT"
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		_, _ = w.Write([]byte(testRSSFeed))
+	}))
+	defer srv.Close()
+
+	// First fetch has no validators and should return them
+	feed, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-123", URL: srv.URL})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.NotNil(t, feed.ETag)
+	require.NotNil(t, feed.LastModified)
+	assert.Equal(t, etag, *feed.ETag)
+	assert.Equal(t, lastModified, *feed.LastModified)
+
+	// Sending them back should short circuit
+	_, entries, err = testFetcher.Feed(context.Background(), seymour.Feed{
+		ID:           "feed-123",
+		URL:          srv.URL,
+		ETag:         feed.ETag,
+		LastModified: feed.LastModified,
+	})
+	assert.ErrorIs(t, err, ErrNotModified)
+	assert.Empty(t, entries)
+}
+
+func TestDiscover(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rss", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testRSSFeed))
+	})
+	mux.HandleFunc("/blog", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<!DOCTYPE html>
+<html>
+  <head>
+    <link rel="stylesheet" href="/style.css">
+    <link rel="alternate" type="application/rss+xml" href="/rss">
+    <link rel="alternate" type="application/atom+xml" href="https://example.com/atom.xml">
+  </head>
+  <body></body>
+</html>`))
+	})
+	mux.HandleFunc("/bare", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>No feeds here</title></head></html>`))
+	})
+	mux.Handle("/old-rss", http.RedirectHandler("/rss", http.StatusMovedPermanently))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+	}{
+		{
+			name:     "feed url is returned as is",
+			path:     "/rss",
+			expected: []string{srv.URL + "/rss"},
+		},
+		{
+			name:     "alternate links are resolved",
+			path:     "/blog",
+			expected: []string{srv.URL + "/rss", "https://example.com/atom.xml"},
+		},
+		{
+			name:     "moved feed url is followed",
+			path:     "/old-rss",
+			expected: []string{srv.URL + "/rss"},
+		},
+		{
+			name:     "falls back to common paths",
+			path:     "/bare",
+			expected: []string{srv.URL + "/rss"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testFetcher.Discover(context.Background(), srv.URL+tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestFeed_Content(t *testing.T) {
+	const feedXML = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>Content Feed</title>
+    <item>
+      <title>Full Post</title>
+      <guid>content-1</guid>
+      <description>A &lt;b&gt;short&lt;/b&gt; teaser</description>
+      <content:encoded><![CDATA[<p>The <em>whole</em> post.</p><script>alert(1)</script>]]></content:encoded>
+    </item>
+  </channel>
+</rss>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(feedXML))
+	}))
+	defer srv.Close()
+
+	_, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-content", URL: srv.URL})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	assert.Equal(t, "A short teaser", entries[0].Description)
+	assert.Equal(t, "<p>The <em>whole</em> post.</p>", entries[0].Content)
+}
+
+func TestFeed_Media(t *testing.T) {
+	const podcastXML = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title>Test Podcast</title>
+    <item>
+      <title>Episode One</title>
+      <guid>episode-1</guid>
+      <enclosure url="https://example.com/ep1.mp3" type="audio/mpeg" length="12345"/>
+      <itunes:duration>1:02:03</itunes:duration>
+      <itunes:image href="https://example.com/ep1.jpg"/>
+    </item>
+  </channel>
+</rss>`
+	const youtubeXML = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+  <title>Test Channel</title>
+  <entry>
+    <id>yt:video:abc</id>
+    <title>A Video</title>
+    <link rel="alternate" href="https://www.youtube.com/watch?v=abc"/>
+    <updated>2024-01-01T12:00:00Z</updated>
+    <media:group>
+      <media:content url="https://www.youtube.com/v/abc" type="application/x-shockwave-flash"/>
+      <media:thumbnail url="https://i.ytimg.com/vi/abc/hqdefault.jpg"/>
+    </media:group>
+  </entry>
+</feed>`
+	const invalidLengthsXML = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/">
+  <channel>
+    <title>Test Podcast</title>
+    <item>
+      <title>Episode Two</title>
+      <guid>episode-2</guid>
+      <enclosure url="https://example.com/ep2.mp3" type="audio/mpeg" length="unknown"/>
+      <media:content url="https://example.com/ep2.mp4" type="video/mp4" fileSize="" duration="12.5"/>
+    </item>
+  </channel>
+</rss>`
+	// An episode with the given itunes:duration
+	const durationXML = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title>Test Podcast</title>
+    <item>
+      <title>Episode Four</title>
+      <guid>episode-4</guid>
+      <enclosure url="https://example.com/ep4.mp3" type="audio/mpeg" length="100"/>
+      <itunes:duration>%s</itunes:duration>
+    </item>
+  </channel>
+</rss>`
+	const invalidLengthsJSON = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Test Podcast",
+  "items": [{
+    "id": "episode-3",
+    "title": "Episode Three",
+    "attachments": [{"url": "https://example.com/ep3.mp3", "mime_type": "audio/mpeg", "size_in_bytes": "unknown", "duration_in_seconds": 61.5}]
+  }]
+}`
+
 	tests := []struct {
 		name     string
+		body     string
+		expected []seymour.EntryMedia
+	}{
+		{
+			name: "podcast enclosure",
+			body: podcastXML,
+			expected: []seymour.EntryMedia{{
+				URL:          "https://example.com/ep1.mp3",
+				MIMEType:     "audio/mpeg",
+				Length:       12345,
+				Duration:     3723,
+				ThumbnailURL: "https://example.com/ep1.jpg",
+			}},
+		},
+		{
+			name: "youtube media group",
+			body: youtubeXML,
+			expected: []seymour.EntryMedia{{
+				URL:          "https://www.youtube.com/v/abc",
+				MIMEType:     "application/x-shockwave-flash",
+				ThumbnailURL: "https://i.ytimg.com/vi/abc/hqdefault.jpg",
+			}},
+		},
+		{
+			name: "invalid lengths",
+			body: invalidLengthsXML,
+			expected: []seymour.EntryMedia{
+				{URL: "https://example.com/ep2.mp3", MIMEType: "audio/mpeg"},
+				{URL: "https://example.com/ep2.mp4", MIMEType: "video/mp4", Duration: 12},
+			},
+		},
+		{
+			name: "invalid json lengths",
+			body: invalidLengthsJSON,
+			expected: []seymour.EntryMedia{
+				{URL: "https://example.com/ep3.mp3", MIMEType: "audio/mpeg", Duration: 61},
+			},
+		},
+		{
+			name: "fractional duration",
+			body: fmt.Sprintf(durationXML, "12.5"),
+			expected: []seymour.EntryMedia{
+				{URL: "https://example.com/ep4.mp3", MIMEType: "audio/mpeg", Length: 100, Duration: 12},
+			},
+		},
+		{
+			name: "fractional clock duration",
+			body: fmt.Sprintf(durationXML, "1:02:03.5"),
+			expected: []seymour.EntryMedia{
+				{URL: "https://example.com/ep4.mp3", MIMEType: "audio/mpeg", Length: 100, Duration: 3723},
+			},
+		},
+		{
+			name: "padded clock duration",
+			body: fmt.Sprintf(durationXML, " 1 : 02 : 03 "),
+			expected: []seymour.EntryMedia{
+				{URL: "https://example.com/ep4.mp3", MIMEType: "audio/mpeg", Length: 100, Duration: 3723},
+			},
+		},
+		{
+			name: "invalid duration",
+			body: fmt.Sprintf(durationXML, "1:-2:03"),
+			expected: []seymour.EntryMedia{
+				{URL: "https://example.com/ep4.mp3", MIMEType: "audio/mpeg", Length: 100},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			_, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-media", URL: srv.URL})
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			assert.Equal(t, tt.expected, entries[0].Media)
+		})
+	}
+}
+
+func TestFeed_AuthorsAndCategories(t *testing.T) {
+	const rssXML = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel>
+    <title>Test RSS Feed</title>
+    <item>
+      <title>Sponsored Post</title>
+      <guid>rss-authors-1</guid>
+      <author>jane@example.com (Jane Doe)</author>
+      <dc:creator>John Smith</dc:creator>
+      <dc:creator>Jane Doe</dc:creator>
+      <category>Sponsored</category>
+      <category domain="https://example.com/tags">Tech</category>
+    </item>
+  </channel>
+</rss>`
+	const atomXML = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Test Atom Feed</title>
+  <author><name>Feed Author</name></author>
+  <entry>
+    <title>Inherits the author</title>
+    <id>atom-authors-1</id>
+    <category term="go" label="Go"/>
+    <category term="databases"/>
+  </entry>
+  <entry>
+    <title>Has its own author</title>
+    <id>atom-authors-2</id>
+    <author><name>Entry Author</name></author>
+  </entry>
+</feed>`
+
+	tests := []struct {
+		name               string
+		body               string
+		expectedAuthors    [][]string
+		expectedCategories [][]string
+	}{
+		{
+			name:               "rss author and dc:creator",
+			body:               rssXML,
+			expectedAuthors:    [][]string{{"Jane Doe", "John Smith"}},
+			expectedCategories: [][]string{{"Sponsored", "Tech"}},
+		},
+		{
+			name:               "atom authors and category terms",
+			body:               atomXML,
+			expectedAuthors:    [][]string{{"Feed Author"}, {"Entry Author"}},
+			expectedCategories: [][]string{{"Go", "databases"}, {}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			_, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-authors", URL: srv.URL})
+			require.NoError(t, err)
+			require.Len(t, entries, len(tt.expectedAuthors))
+			for i, entry := range entries {
+				assert.Equal(t, tt.expectedAuthors[i], entry.Authors)
+				assert.Equal(t, tt.expectedCategories[i], entry.Categories)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
 		input    string
-		expected string
+		expected time.Time
+	}{
+		{input: "Mon, 01 Jan 2024 12:00:00 GMT", expected: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "Mon, 01 Jan 2024 12:00:00 +0000", expected: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "Mon, 01 Jan 2024 07:00:00 EST", expected: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "Mon, 1 Jan 2024 04:00:00 PST", expected: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "Mon, 01 Jan 2024 13:00 CET", expected: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "01 Jan 2024 12:00:00 +0000", expected: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "2024-01-01T12:00:00Z", expected: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "2024-01-01T13:00:00+01:00", expected: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "2024-01-01 12:00:00", expected: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "2024-01-01", expected: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{input: "January 1, 2024", expected: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := parseDate(tt.input)
+			require.True(t, ok)
+			assert.True(t, tt.expected.Equal(got), "expected %s, got %s", tt.expected, got)
+		})
+	}
+}
+
+func TestFeed_DateFallbacks(t *testing.T) {
+	const atomXML = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Test Atom Feed</title>
+  <entry>
+    <id>dates-1</id>
+    <title>Published and updated</title>
+    <published>2024-01-01T12:00:00Z</published>
+    <updated>2024-02-01T12:00:00Z</updated>
+  </entry>
+  <entry>
+    <id>dates-2</id>
+    <title>Garbage date</title>
+    <updated>sometime last week</updated>
+  </entry>
+</feed>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(atomXML))
+	}))
+	defer srv.Close()
+
+	feed, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-dates", URL: srv.URL})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	// Published wins over updated
+	assert.True(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).Equal(entries[0].PublishTime.Time))
+
+	// Unparseable dates fall back to the fetch time and are warned about
+	assert.WithinDuration(t, time.Now(), entries[1].PublishTime.Time, time.Minute)
+	require.NotNil(t, feed.ParseWarning)
+	assert.Contains(t, *feed.ParseWarning, "sometime last week")
+}
+
+func TestFeed_SynthesizedGUID(t *testing.T) {
+	const feedXML = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>No GUIDs</title>
+    <item>
+      <title>Dated</title>
+      <link>https://example.com/dated</link>
+      <pubDate>Mon, 01 Jan 2024 12:00:00 GMT</pubDate>
+    </item>
+    <item>
+      <title>Undated</title>
+      <link>https://example.com/undated</link>
+    </item>
+  </channel>
+</rss>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(feedXML))
+	}))
+	defer srv.Close()
+
+	_, first, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-guids", URL: srv.URL})
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+
+	assert.Equal(t, "https://example.com/dated|Dated|2024-01-01T12:00:00Z", first[0].GUID)
+	assert.Equal(t, "https://example.com/undated|Undated|", first[1].GUID)
+
+	// Syncing again yields the same identities, even though the undated one falls back to the fetch time
+	_, second, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-guids", URL: srv.URL})
+	require.NoError(t, err)
+	require.Len(t, second, 2)
+	assert.Equal(t, first[0].GUID, second[0].GUID)
+	assert.Equal(t, first[1].GUID, second[1].GUID)
+}
+
+func TestSanitize_RuneSafeTruncation(t *testing.T) {
+	// Each of these is three bytes, so 2048 bytes lands mid-rune
+	s := strings.Repeat("日", 1000)
+
+	got := sanitize(s)
+	assert.True(t, utf8.ValidString(got))
+	assert.LessOrEqual(t, len(got), maxDescriptionLen)
+	assert.Equal(t, strings.Repeat("日", maxDescriptionLen/3), got)
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		input       string
+		expected    string
 	}{
 		{
 			name:     "rss feed",
@@ -128,17 +749,792 @@ func TestDetectFormat(t *testing.T) {
 			input:    `<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"></feed>`,
 			expected: "atom",
 		},
+		{
+			name:     "rdf feed",
+			input:    `<?xml version="1.0"?><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"></rdf:RDF>`,
+			expected: "rdf",
+		},
 		{
 			name:     "empty input defaults to rss",
 			input:    "",
 			expected: "rss",
 		},
+		{
+			name:     "json feed by leading brace",
+			input:    ` {"version": "https://jsonfeed.org/version/1.1"}`,
+			expected: "json",
+		},
+		{
+			name:        "json feed by content type",
+			contentType: "application/feed+json; charset=utf-8",
+			input:       "",
+			expected:    "json",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := detectFormat([]byte(tt.input))
+			got := detectFormat(tt.contentType, []byte(tt.input))
 			assert.Equal(t, tt.expected, got)
 		})
 	}
 }
+
+func TestFeed_Charsets(t *testing.T) {
+	encode := func(t *testing.T, enc encoding.Encoding, s string) []byte {
+		t.Helper()
+		b, err := enc.NewEncoder().Bytes([]byte(s))
+		require.NoError(t, err)
+		return b
+	}
+	rss := func(label, title string) string {
+		prolog := `<?xml version="1.0"?>`
+		if label != "" {
+			prolog = `<?xml version="1.0" encoding="` + label + `"?>`
+		}
+		return prolog + `<rss version="2.0"><channel><title>` + title + `</title>` +
+			`<item><title>` + title + `</title><link>https://example.com/1</link><guid>1</guid></item>` +
+			`</channel></rss>`
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        func(t *testing.T) []byte
+		expected    string
+	}{
+		{
+			name:        "iso-8859-1 from the prolog",
+			contentType: "application/rss+xml",
+			body:        func(t *testing.T) []byte { return encode(t, charmap.ISO8859_1, rss("ISO-8859-1", "Café")) },
+			expected:    "Café",
+		},
+		{
+			name: "windows-1252 from the content type",
+			// The server's charset is used when the prolog doesn't declare one
+			contentType: "application/rss+xml; charset=windows-1252",
+			body:        func(t *testing.T) []byte { return encode(t, charmap.Windows1252, rss("", "“Quoted” — café")) },
+			expected:    "“Quoted” — café",
+		},
+		{
+			name: "content type wins over the prolog",
+			// Transcoded by the server, which left the prolog saying what it was
+			contentType: "text/xml; charset=utf-8",
+			body:        func(t *testing.T) []byte { return []byte(rss("KOI8-R", "Новости")) },
+			expected:    "Новости",
+		},
+		{
+			name:        "prolog without a charset in the content type",
+			contentType: "text/xml",
+			body:        func(t *testing.T) []byte { return encode(t, charmap.KOI8R, rss("KOI8-R", "Новости")) },
+			expected:    "Новости",
+		},
+		{
+			name:        "shift_jis",
+			contentType: "application/rss+xml",
+			body: func(t *testing.T) []byte {
+				return encode(t, japanese.ShiftJIS, rss("Shift_JIS", "日本語のニュース"))
+			},
+			expected: "日本語のニュース",
+		},
+		{
+			name: "utf-16 with a byte order mark",
+			body: func(t *testing.T) []byte {
+				return encode(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), rss("UTF-16", "Ünïcödé"))
+			},
+			expected: "Ünïcödé",
+		},
+		{
+			name:     "utf-8 byte order mark",
+			body:     func(t *testing.T) []byte { return append([]byte("\xEF\xBB\xBF"), rss("", "Café")...) },
+			expected: "Café",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body(t)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				_, _ = w.Write(body)
+			}))
+			defer srv.Close()
+
+			feed, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-charset", URL: srv.URL})
+			require.NoError(t, err)
+			require.NotNil(t, feed.Title)
+			assert.Equal(t, tt.expected, *feed.Title)
+			require.Len(t, entries, 1)
+			assert.Equal(t, tt.expected, entries[0].Title)
+		})
+	}
+}
+
+func TestFeed_UnsupportedCharset(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="x-made-up"?><rss version="2.0"><channel></channel></rss>`))
+	}))
+	defer srv.Close()
+
+	_, _, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-charset", URL: srv.URL})
+	assert.ErrorContains(t, err, "unsupported charset")
+}
+
+func TestSchedule_Interval(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	daily := func(n int) []time.Time {
+		times := []time.Time{}
+		for i := range n {
+			times = append(times, now.Add(-time.Duration(i+1)*24*time.Hour))
+		}
+		return times
+	}
+
+	tests := []struct {
+		name     string
+		sched    schedule
+		expected time.Duration
+	}{
+		{
+			name:     "no dates uses the default",
+			sched:    schedule{},
+			expected: defaultSyncInterval,
+		},
+		{
+			name:     "daily posts are checked twice a day",
+			sched:    schedule{published: daily(10)},
+			expected: 12 * time.Hour,
+		},
+		{
+			name: "busy feeds are limited to the minimum",
+			sched: schedule{published: []time.Time{
+				now.Add(-time.Minute), now.Add(-2 * time.Minute), now.Add(-3 * time.Minute),
+			}},
+			expected: minSyncInterval,
+		},
+		{
+			name:     "quiet feeds are limited to the maximum",
+			sched:    schedule{published: []time.Time{now.Add(-90 * 24 * time.Hour)}},
+			expected: maxSyncInterval,
+		},
+		{
+			name:     "ttl is a lower bound",
+			sched:    schedule{published: []time.Time{now.Add(-time.Minute)}, ttl: 3 * time.Hour},
+			expected: 3 * time.Hour,
+		},
+		{
+			name:     "max-age is a lower bound",
+			sched:    schedule{maxAge: 2 * time.Hour},
+			expected: 2 * time.Hour,
+		},
+		{
+			name:     "future dates are ignored",
+			sched:    schedule{published: []time.Time{now.Add(48 * time.Hour)}},
+			expected: defaultSyncInterval,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.sched.interval(now))
+		})
+	}
+}
+
+func TestSchedule_NextSkips(t *testing.T) {
+	// A Saturday
+	now := time.Date(2024, 1, 6, 20, 30, 0, 0, time.UTC)
+
+	sched := schedule{
+		skipHours: []int{22, 23, 0, 1},
+		skipDays:  []time.Weekday{time.Sunday},
+	}
+	assert.Equal(t, now.Add(time.Hour), sched.next(now, time.Hour))
+	// Skips the rest of the night, and then all of Sunday
+	assert.Equal(t, time.Date(2024, 1, 8, 2, 0, 0, 0, time.UTC), sched.next(now, 2*time.Hour))
+}
+
+func TestFeed_Schedule(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=7200")
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0">
+	<channel>
+		<title>Scheduled</title>
+		<ttl>60</ttl>
+		<skipHours><hour>0</hour><hour>24</hour><hour>25</hour></skipHours>
+		<skipDays><day>Saturday</day><day>Someday</day></skipDays>
+		<item><title>One</title><guid>1</guid></item>
+	</channel>
+</rss>`))
+	}))
+	defer srv.Close()
+
+	before := time.Now()
+	feed, _, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-schedule", URL: srv.URL})
+	require.NoError(t, err)
+
+	// Max-age asked for longer than the ttl, and the undated entry doesn't count as posting activity
+	assert.Equal(t, int64(7200), feed.SyncInterval)
+	require.NotNil(t, feed.NextSyncAt)
+	next := feed.NextSyncAt.Time
+	assert.False(t, next.Before(before.Add(2*time.Hour)))
+	assert.NotEqual(t, 0, next.Hour())
+	assert.NotEqual(t, time.Saturday, next.Weekday())
+	// Kept for the syncs that find it unchanged
+	require.NotNil(t, feed.SkipHours)
+	require.NotNil(t, feed.SkipDays)
+	assert.Equal(t, "0", *feed.SkipHours)
+	assert.Equal(t, "Saturday", *feed.SkipDays)
+}
+
+func TestReschedule(t *testing.T) {
+	// A Saturday
+	now := time.Date(2024, 1, 6, 20, 30, 0, 0, time.UTC)
+	hours, days := "22,23,0,1", "Sunday"
+	feed := seymour.Feed{SyncInterval: int64(time.Hour / time.Second), SkipHours: &hours, SkipDays: &days}
+
+	assert.Equal(t, now.Add(time.Hour), Reschedule(feed, 0, now).Time)
+	// The server's max-age pushes it into the skipped night, and then all of Sunday
+	assert.Equal(t, time.Date(2024, 1, 8, 2, 0, 0, 0, time.UTC), Reschedule(feed, 2*time.Hour, now).Time)
+	// Without an interval or anything to skip
+	assert.Equal(t, now.Add(defaultSyncInterval), Reschedule(seymour.Feed{}, 0, now).Time)
+}
+
+func TestFeed_NotModifiedSchedule(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=7200")
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer srv.Close()
+
+	before := time.Now()
+	etag, skipped := `"abc"`, strconv.Itoa(before.Add(2*time.Hour).UTC().Hour())
+	feed, _, err := testFetcher.Feed(context.Background(), seymour.Feed{
+		ID:           "feed-unchanged",
+		URL:          srv.URL,
+		ETag:         &etag,
+		SyncInterval: int64(minSyncInterval / time.Second),
+		SkipHours:    &skipped,
+	})
+	require.ErrorIs(t, err, ErrNotModified)
+
+	// The max-age on the 304 holds it back past the interval, and out of the hour it skips
+	require.NotNil(t, feed.NextSyncAt)
+	next := feed.NextSyncAt.Time
+	assert.False(t, next.Before(before.Add(2*time.Hour)))
+	assert.NotEqual(t, skipped, strconv.Itoa(next.Hour()))
+}
+
+func TestFeed_InvalidScheduleHints(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0">
+	<channel>
+		<title>Scheduled</title>
+		<ttl>an hour</ttl>
+		<skipHours><hour></hour><hour>noon</hour><hour> 3 </hour></skipHours>
+		<item><title>One</title><guid>1</guid></item>
+	</channel>
+</rss>`))
+	}))
+	defer srv.Close()
+
+	// The hints are only hints, the feed's still read without them
+	feed, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-schedule", URL: srv.URL})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, int64(defaultSyncInterval/time.Second), feed.SyncInterval)
+
+	assert.Equal(t, []int{3}, skipHours([]string{"", "noon", " 3 ", "25"}))
+	assert.Equal(t, 90*time.Minute, rssTTL(" 90 "))
+	assert.Zero(t, rssTTL("-5"))
+}
+
+func TestFeed_RetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		expected   func(now time.Time) time.Time
+	}{
+		{
+			name:       "seconds",
+			status:     http.StatusTooManyRequests,
+			retryAfter: "3600",
+			expected:   func(now time.Time) time.Time { return now.Add(time.Hour) },
+		},
+		{
+			name:       "http date",
+			status:     http.StatusServiceUnavailable,
+			retryAfter: "Wed, 21 Oct 2099 07:28:00 GMT",
+			// Limited to a week from now
+			expected: func(now time.Time) time.Time { return now.Add(maxRetryAfter) },
+		},
+		{
+			name:     "missing",
+			status:   http.StatusTooManyRequests,
+			expected: func(now time.Time) time.Time { return now.Add(defaultRetryAfter) },
+		},
+		{
+			name:       "unusable",
+			status:     http.StatusServiceUnavailable,
+			retryAfter: "soon",
+			expected:   func(now time.Time) time.Time { return now.Add(defaultRetryAfter) },
+		},
+		{
+			name:       "in the past",
+			status:     http.StatusTooManyRequests,
+			retryAfter: "Wed, 21 Oct 2015 07:28:00 GMT",
+			expected:   func(now time.Time) time.Time { return now.Add(defaultRetryAfter) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			now := time.Now()
+			_, _, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-retry", URL: srv.URL})
+			retryErr := &RetryAfterError{}
+			require.ErrorAs(t, err, &retryErr)
+			assert.Equal(t, tt.status, retryErr.StatusCode)
+			assert.WithinDuration(t, tt.expected(now), retryErr.Until, 5*time.Second)
+		})
+	}
+}
+
+func TestTemporary(t *testing.T) {
+	for status, temporary := range map[int]bool{
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+		http.StatusRequestTimeout:      true,
+		http.StatusNotFound:            false,
+		http.StatusGone:                false,
+		http.StatusForbidden:           false,
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		_, _, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-failing", URL: srv.URL})
+		srv.Close()
+
+		require.Error(t, err)
+		assert.Equal(t, temporary, Temporary(err), status)
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		_, _, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-failing", URL: "http://127.0.0.1:1/feed"})
+		require.Error(t, err)
+		assert.True(t, Temporary(err))
+	})
+
+	t.Run("not a feed", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("<rss><channel>"))
+		}))
+		defer srv.Close()
+
+		_, _, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-failing", URL: srv.URL})
+		require.Error(t, err)
+		assert.False(t, Temporary(err))
+	})
+
+	t.Run("blocked", func(t *testing.T) {
+		f, err := NewFetcher(FetcherOptions{Guard: &Guard{}})
+		require.NoError(t, err)
+
+		_, _, err = f.Feed(context.Background(), seymour.Feed{ID: "feed-failing", URL: "http://127.0.0.1:1/feed"})
+		require.ErrorIs(t, err, ErrBlockedAddress)
+		assert.False(t, Temporary(err))
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{failures: 1, expected: minSyncInterval},
+		{failures: 2, expected: 2 * minSyncInterval},
+		{failures: 4, expected: 8 * minSyncInterval},
+		{failures: 9, expected: maxSyncInterval},
+		{failures: 100, expected: maxSyncInterval},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, now.Add(tt.expected), Backoff(tt.failures, now).Time, "failures: %d", tt.failures)
+	}
+}
+
+func TestFeed_PermanentRedirects(t *testing.T) {
+	tests := []struct {
+		name     string
+		hops     []int // Status codes of the redirects, in order
+		expected string
+	}{
+		{
+			name:     "no redirects",
+			expected: "",
+		},
+		{
+			name:     "permanent chain",
+			hops:     []int{http.StatusMovedPermanently, http.StatusPermanentRedirect},
+			expected: "/2",
+		},
+		{
+			name:     "temporary redirect",
+			hops:     []int{http.StatusFound},
+			expected: "",
+		},
+		{
+			name:     "permanent then temporary",
+			hops:     []int{http.StatusMovedPermanently, http.StatusTemporaryRedirect},
+			expected: "/1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Each hop redirects from /n to /n+1, until the feed itself
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var hop int
+				if r.URL.Path != "/" {
+					_, _ = fmt.Sscanf(r.URL.Path, "/%d", &hop)
+				}
+				if hop < len(tt.hops) {
+					http.Redirect(w, r, fmt.Sprintf("/%d", hop+1), tt.hops[hop])
+					return
+				}
+
+				_, _ = w.Write([]byte(testRSSFeed))
+			}))
+			defer srv.Close()
+
+			feed, _, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-redirect", URL: srv.URL + "/"})
+			require.NoError(t, err)
+
+			expected := tt.expected
+			if expected != "" {
+				expected = srv.URL + expected
+			}
+			assert.Equal(t, expected, feed.URL)
+		})
+	}
+}
+
+func TestFeed_WebSubLinks(t *testing.T) {
+	tests := []struct {
+		name          string
+		contentType   string
+		link          string
+		body          string
+		expectedHub   string
+		expectedTopic string
+	}{
+		{
+			name: "rss atom links",
+			body: `<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+	<channel>
+		<title>Pushed</title>
+		<atom:link rel="hub" href="https://hub.example.com/"/>
+		<atom:link rel="self" href="https://example.com/feed.xml"/>
+	</channel>
+</rss>`,
+			expectedHub:   "https://hub.example.com/",
+			expectedTopic: "https://example.com/feed.xml",
+		},
+		{
+			name: "atom links",
+			body: `<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Pushed</title>
+	<link rel="self" href="https://example.com/atom.xml"/>
+	<link rel="hub" href="https://hub.example.com/"/>
+</feed>`,
+			expectedHub:   "https://hub.example.com/",
+			expectedTopic: "https://example.com/atom.xml",
+		},
+		{
+			name:          "json feed hubs",
+			contentType:   "application/feed+json",
+			body:          `{"version": "https://jsonfeed.org/version/1.1", "title": "Pushed", "feed_url": "https://example.com/feed.json", "hubs": [{"type": "WebSub", "url": "https://hub.example.com/"}], "items": []}`,
+			expectedHub:   "https://hub.example.com/",
+			expectedTopic: "https://example.com/feed.json",
+		},
+		{
+			name:          "link headers win",
+			link:          `<https://other-hub.example.com/>; rel="hub", <https://example.com/canonical.xml>; rel="self"`,
+			body:          `<?xml version="1.0"?><rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><atom:link rel="hub" href="https://hub.example.com/"/></channel></rss>`,
+			expectedHub:   "https://other-hub.example.com/",
+			expectedTopic: "https://example.com/canonical.xml",
+		},
+		{
+			name:          "no hub",
+			body:          testRSSFeed,
+			expectedHub:   "",
+			expectedTopic: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				if tt.link != "" {
+					w.Header().Set("Link", tt.link)
+				}
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			feed, _, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-websub", URL: srv.URL})
+			require.NoError(t, err)
+			require.NotNil(t, feed.HubURL)
+			require.NotNil(t, feed.TopicURL)
+			assert.Equal(t, tt.expectedHub, *feed.HubURL)
+			assert.Equal(t, tt.expectedTopic, *feed.TopicURL)
+		})
+	}
+}
+
+func TestWebSub_SubscribeAndPush(t *testing.T) {
+	const (
+		topic    = "https://example.com/feed.xml"
+		callback = "https://seymour.example.com/api/websub/feed-websub"
+		secret   = "shh"
+	)
+
+	// A stand-in hub that checks the subscription request
+	var subscribed url.Values
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		subscribed = r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	err := testFetcher.Subscribe(context.Background(), hub.URL, topic, callback, secret, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "subscribe", subscribed.Get("hub.mode"))
+	assert.Equal(t, topic, subscribed.Get("hub.topic"))
+	assert.Equal(t, callback, subscribed.Get("hub.callback"))
+	assert.Equal(t, secret, subscribed.Get("hub.secret"))
+	assert.Equal(t, "86400", subscribed.Get("hub.lease_seconds"))
+
+	// The hub then pushes the feed, signed with the secret
+	body := []byte(testRSSFeed)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	assert.True(t, VerifySignature(signature, secret, body))
+	assert.False(t, VerifySignature(signature, "wrong secret", body))
+	assert.False(t, VerifySignature(signature, secret, append(body, ' ')))
+	assert.False(t, VerifySignature("md5="+hex.EncodeToString(mac.Sum(nil)), secret, body))
+	assert.False(t, VerifySignature("", secret, body))
+
+	_, entries, err := Parse("feed-websub", "application/rss+xml", body)
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	assert.Equal(t, "feed-websub", entries[0].FeedID)
+}
+
+func TestWebSub_HubRejects(t *testing.T) {
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "topic not allowed", http.StatusForbidden)
+	}))
+	defer hub.Close()
+
+	err := testFetcher.Subscribe(context.Background(), hub.URL, "https://example.com/feed.xml", "https://seymour.example.com/cb", "shh", time.Hour)
+	assert.ErrorContains(t, err, "topic not allowed")
+}
+
+func TestFetcher_Encodings(t *testing.T) {
+	compress := map[string]func(w io.Writer) io.WriteCloser{
+		"gzip":    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"br":      func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+		"raw deflate": func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		},
+	}
+
+	for name, newWriter := range compress {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Contains(t, r.Header.Get("Accept-Encoding"), strings.TrimPrefix(name, "raw "))
+				w.Header().Set("Content-Type", "application/rss+xml")
+				w.Header().Set("Content-Encoding", strings.TrimPrefix(name, "raw "))
+				cw := newWriter(w)
+				_, _ = cw.Write([]byte(testRSSFeed))
+				_ = cw.Close()
+			}))
+			defer srv.Close()
+
+			feed, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-encoded", URL: srv.URL})
+			require.NoError(t, err)
+			assert.Equal(t, "Test RSS Feed", *feed.Title)
+			assert.Len(t, entries, 2)
+		})
+	}
+}
+
+func TestFetcher_UnsupportedEncoding(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "zstd")
+		_, _ = w.Write([]byte("whatever"))
+	}))
+	defer srv.Close()
+
+	_, _, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-zstd", URL: srv.URL})
+	assert.ErrorContains(t, err, "unsupported content encoding")
+}
+
+func TestFetcher_UserAgent(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("User-Agent"))
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(testRSSFeed))
+	}))
+	defer srv.Close()
+
+	_, _, err := testFetcher.Feed(context.Background(), seymour.Feed{ID: "feed-ua", URL: srv.URL})
+	require.NoError(t, err)
+
+	custom, err := NewFetcher(FetcherOptions{UserAgent: "test-agent/2.0"})
+	require.NoError(t, err)
+	_, _, err = custom.Feed(context.Background(), seymour.Feed{ID: "feed-ua", URL: srv.URL})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{DefaultUserAgent, "test-agent/2.0"}, got)
+}
+
+func TestFetcher_MaxBodySize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Compresses well, so the limit has to apply to what it decompresses to
+		w.Header().Set("Content-Encoding", "gzip")
+		gw := gzip.NewWriter(w)
+		_, _ = gw.Write([]byte(testRSSFeed + strings.Repeat(" ", 1<<20)))
+		_ = gw.Close()
+	}))
+	defer srv.Close()
+
+	f, err := NewFetcher(FetcherOptions{MaxBodySize: 1 << 20})
+	require.NoError(t, err)
+
+	_, _, err = f.Feed(context.Background(), seymour.Feed{ID: "feed-huge", URL: srv.URL})
+	assert.ErrorContains(t, err, "larger than 1048576 bytes")
+}
+
+func TestFetcher_ReadTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(testRSSFeed[:100]))
+		w.(http.Flusher).Flush()
+
+		// Trickles the rest out slower than the fetcher will wait for
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	f, err := NewFetcher(FetcherOptions{ReadTimeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, _, err = f.Feed(context.Background(), seymour.Feed{ID: "feed-slow", URL: srv.URL})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestFetcher_Proxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A proxy is asked for the absolute url
+		assert.Equal(t, "feeds.example.com", r.URL.Host)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(testRSSFeed))
+	}))
+	defer proxy.Close()
+
+	f, err := NewFetcher(FetcherOptions{ProxyURL: proxy.URL})
+	require.NoError(t, err)
+
+	feed, _, err := f.Feed(context.Background(), seymour.Feed{ID: "feed-proxied", URL: "http://feeds.example.com/rss"})
+	require.NoError(t, err)
+	assert.Equal(t, "Test RSS Feed", *feed.Title)
+
+	_, err = NewFetcher(FetcherOptions{ProxyURL: "ftp://proxy.example.com"})
+	assert.ErrorContains(t, err, "unsupported proxy scheme")
+}
+
+func TestFetcher_EnvironmentProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "feeds.example.com", r.URL.Host)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(testRSSFeed))
+	}))
+	defer proxy.Close()
+	t.Setenv("HTTP_PROXY", proxy.URL)
+
+	f, err := NewFetcher(FetcherOptions{})
+	require.NoError(t, err)
+
+	feed, _, err := f.Feed(context.Background(), seymour.Feed{ID: "feed-proxied", URL: "http://feeds.example.com/rss"})
+	require.NoError(t, err)
+	assert.Equal(t, "Test RSS Feed", *feed.Title)
+
+	t.Run("still guarded", func(t *testing.T) {
+		guard, err := NewGuard(nil)
+		require.NoError(t, err)
+		f, err := NewFetcher(FetcherOptions{Guard: guard})
+		require.NoError(t, err)
+
+		_, _, err = f.Feed(context.Background(), seymour.Feed{ID: "feed-proxied", URL: "http://10.0.0.1/feed"})
+		assert.ErrorIs(t, err, ErrBlockedAddress)
+	})
+}
+
+// Answers every request itself instead of going over the network.
+type fakeTransport func(r *http.Request) *http.Response
+
+func (f fakeTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r), nil
+}
+
+func TestFetcher_Transport(t *testing.T) {
+	f, err := NewFetcher(FetcherOptions{
+		Transport: fakeTransport(func(r *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {"application/rss+xml"}},
+				Body:       io.NopCloser(strings.NewReader(testRSSFeed)),
+				Request:    r,
+			}
+		}),
+	})
+	require.NoError(t, err)
+
+	feed, entries, err := f.Feed(context.Background(), seymour.Feed{ID: "feed-fake", URL: "https://nowhere.invalid/feed"})
+	require.NoError(t, err)
+	assert.Equal(t, "Test RSS Feed", *feed.Title)
+	assert.Len(t, entries, 2)
+}
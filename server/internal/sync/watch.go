@@ -0,0 +1,185 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	readability "github.com/go-shiori/go-readability"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// WatchSource follows a single page, like a pricing page or a policy, for changes to its readable content.
+//
+// Its urls are the page's with watch: in front, like watch:https://example.com/pricing. Each fetch
+// gives one entry that's a snapshot of the page: its normalized text as the content, and a hash of
+// it as the GUID. It's up to whoever keeps the snapshots to compare them, e.g. with [Diff].
+type WatchSource struct{}
+
+func (WatchSource) Kind() seymour.FeedKind { return seymour.FeedKindWatch }
+
+func (WatchSource) Fetch(ctx context.Context, f *Fetcher, feed seymour.Feed) (seymour.Feed, []seymour.FeedEntry, error) {
+	var cfg seymour.WatchConfig
+	if feed.SourceConfig != nil {
+		if err := json.Unmarshal([]byte(*feed.SourceConfig), &cfg); err != nil {
+			return seymour.Feed{}, nil, fmt.Errorf("error decoding watch config: %w", err)
+		}
+	}
+
+	pageURL := strings.TrimPrefix(feed.URL, "watch:")
+	title, text, err := f.Snapshot(ctx, pageURL, cfg)
+	if err != nil {
+		return seymour.Feed{}, nil, err
+	}
+
+	description := "Changes to " + pageURL
+	return seymour.Feed{Title: &title, Description: &description}, []seymour.FeedEntry{{
+		GUID:    SnapshotHash(text),
+		Title:   title,
+		Link:    pageURL,
+		Content: text,
+	}}, nil
+}
+
+// compileIgnores checks the config's selectors, compiling them.
+func compileIgnores(cfg seymour.WatchConfig) ([]cascadia.Sel, error) {
+	sels := make([]cascadia.Sel, 0, len(cfg.Ignore))
+	for _, selector := range cfg.Ignore {
+		if strings.TrimSpace(selector) == "" {
+			continue
+		}
+
+		sel, err := cascadia.Parse(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore selector %q: %w", selector, err)
+		}
+		sels = append(sels, sel)
+	}
+
+	return sels, nil
+}
+
+// ValidateWatchConfig checks that all of the config's selectors parse.
+func ValidateWatchConfig(cfg seymour.WatchConfig) error {
+	_, err := compileIgnores(cfg)
+	return err
+}
+
+// Snapshot fetches the page and reduces it to its readable text, the same extraction the reader
+// view uses, without whatever the config says to ignore.
+//
+// The text is normalized so only actual changes show up between snapshots: a line per paragraph,
+// heading, list item, etc., with whitespace collapsed and empty lines dropped.
+func (f *Fetcher) Snapshot(ctx context.Context, pageURL string, cfg seymour.WatchConfig) (title, text string, err error) {
+	ignores, err := compileIgnores(cfg)
+	if err != nil {
+		return "", "", err
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", "", fmt.Errorf("error parsing url: %w", err)
+	}
+
+	page, err := f.get(ctx, pageURL)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := toUTF8(page.contentType, page.body)
+	if err != nil {
+		return "", "", err
+	}
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return "", "", fmt.Errorf("error parsing page: %w", err)
+	}
+
+	for _, sel := range ignores {
+		for _, n := range cascadia.QueryAll(doc, sel) {
+			if n.Parent != nil {
+				n.Parent.RemoveChild(n)
+			}
+		}
+	}
+
+	title = nodeText(cascadia.Query(doc, titleSel))
+
+	// Pages that aren't article-like, e.g. a pricing table, can be too much for readability: the whole body will do
+	var (
+		content = cascadia.Query(doc, bodySel)
+		parser  = readability.NewParser()
+	)
+	article, err := parser.ParseDocument(doc, base)
+	if err == nil && article.Node != nil && strings.TrimSpace(article.TextContent) != "" {
+		content = article.Node
+		if article.Title != "" {
+			title = article.Title
+		}
+	}
+
+	return title, blockText(content), nil
+}
+
+// SnapshotHash identifies a snapshot's text, to tell if it changed.
+func SnapshotHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// Elements that start a line of their own in a snapshot.
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true, atom.Br: true,
+	atom.Dd: true, atom.Details: true, atom.Div: true, atom.Dl: true, atom.Dt: true,
+	atom.Figcaption: true, atom.Figure: true, atom.Footer: true, atom.Form: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Hr: true, atom.Li: true, atom.Main: true, atom.Nav: true, atom.Ol: true,
+	atom.P: true, atom.Pre: true, atom.Section: true, atom.Summary: true, atom.Table: true,
+	atom.Td: true, atom.Th: true, atom.Tr: true, atom.Ul: true,
+}
+
+// blockText is the node's text with a line for each block, empty for a nil node.
+func blockText(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+
+	var (
+		lines []string
+		line  strings.Builder
+	)
+	flush := func() {
+		if text := strings.Join(strings.Fields(line.String()), " "); text != "" {
+			lines = append(lines, text)
+		}
+		line.Reset()
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			line.WriteString(n.Data)
+			return
+		case n.Type == html.ElementNode && (n.DataAtom == atom.Script || n.DataAtom == atom.Style || n.DataAtom == atom.Noscript || n.DataAtom == atom.Template):
+			return
+		case n.Type == html.ElementNode && blockElements[n.DataAtom]:
+			flush()
+			defer flush()
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	flush()
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,75 @@
+package sync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+const testWatchPage = `<!DOCTYPE html>
+<html>
+<head><title>Pricing</title><style>p { color: red; }</style></head>
+<body>
+<nav><a href="/">Home</a></nav>
+<main>
+	<h1>Plans</h1>
+	<p>The   <b>Pro</b> plan is $10
+		a month.</p>
+	<ul><li>Unlimited feeds</li><li>Email support</li></ul>
+	<p class="updated">Last updated <time>2024-03-02 10:15</time></p>
+</main>
+<script>track()</script>
+</body>
+</html>`
+
+func TestFetcher_Snapshot(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(testWatchPage))
+	}))
+	defer srv.Close()
+
+	title, text, err := testFetcher.Snapshot(context.Background(), srv.URL+"/pricing", seymour.WatchConfig{Ignore: []string{".updated"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Pricing", title)
+	assert.Contains(t, text, "The Pro plan is $10 a month.\nUnlimited feeds\nEmail support")
+	assert.NotContains(t, text, "Last updated")
+	assert.NotContains(t, text, "track()")
+
+	t.Run("as a source", func(t *testing.T) {
+		config := `{"ignore": [".updated"]}`
+		feed, entries, err := testFetcher.Feed(context.Background(), seymour.Feed{
+			ID:           "feed-watched",
+			URL:          "watch:" + srv.URL + "/pricing",
+			Kind:         seymour.FeedKindWatch,
+			SourceConfig: &config,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "Pricing", *feed.Title)
+		require.Len(t, entries, 1)
+		assert.Equal(t, text, entries[0].Content)
+		assert.Equal(t, SnapshotHash(text), entries[0].GUID)
+		assert.Equal(t, srv.URL+"/pricing", entries[0].Link)
+		assert.Equal(t, "feed-watched", entries[0].FeedID)
+	})
+
+	t.Run("without ignoring anything", func(t *testing.T) {
+		_, text, err := testFetcher.Snapshot(context.Background(), srv.URL+"/pricing", seymour.WatchConfig{})
+		require.NoError(t, err)
+		assert.Contains(t, text, "Last updated 2024-03-02 10:15")
+	})
+}
+
+func TestValidateWatchConfig(t *testing.T) {
+	assert.NoError(t, ValidateWatchConfig(seymour.WatchConfig{}))
+	assert.NoError(t, ValidateWatchConfig(seymour.WatchConfig{Ignore: []string{"time", ".banner > p"}}))
+	assert.ErrorContains(t, ValidateWatchConfig(seymour.WatchConfig{Ignore: []string{"a[href"}}), "invalid ignore selector")
+}
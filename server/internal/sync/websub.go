@@ -0,0 +1,126 @@
+package sync
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jdholdren/seymour/internal/seymour"
+)
+
+// The hash functions a hub can sign pushed content with, keyed by the name in X-Hub-Signature.
+//
+// See https://www.w3.org/TR/websub/#signature-validation.
+var signatureHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// Parse parses a feed document that was pushed to us rather than fetched, e.g. by a WebSub hub.
+func Parse(feedID, contentType string, body []byte) (seymour.Feed, []seymour.FeedEntry, error) {
+	return parse(feedID, contentType, body, &schedule{}, &paging{})
+}
+
+// Subscribe asks the hub to push updates to the topic to the callback for the length of the lease.
+//
+// Hubs verify the intent asynchronously by calling the callback, so a successful return only
+// means the request was accepted.
+func (f *Fetcher) Subscribe(ctx context.Context, hubURL, topicURL, callbackURL, secret string, lease time.Duration) error {
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {topicURL},
+		"hub.callback":      {callbackURL},
+		"hub.secret":        {secret},
+		"hub.lease_seconds": {strconv.FormatInt(int64(lease/time.Second), 10)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hubURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("error creating hub request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, body, err := f.do(req)
+	if err != nil {
+		return fmt.Errorf("error subscribing with hub: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg := body[:min(len(body), 512)]
+		return fmt.Errorf("hub rejected subscription with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// VerifySignature checks the X-Hub-Signature a hub sent with pushed content against the
+// secret the subscription was made with.
+func VerifySignature(signature, secret string, body []byte) bool {
+	method, sig, ok := strings.Cut(signature, "=")
+	if !ok {
+		return false
+	}
+	newHash, ok := signatureHashes[strings.ToLower(method)]
+	if !ok {
+		return false
+	}
+	expected, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// webSubLinks picks the hub and self links out of a feed's links.
+func webSubLinks(links []feedLink) (string, string) {
+	var hub, self string
+	for _, l := range links {
+		switch {
+		case hub == "" && l.Rel == "hub":
+			hub = strings.TrimSpace(l.Href)
+		case self == "" && l.Rel == "self":
+			self = strings.TrimSpace(l.Href)
+		}
+	}
+
+	return hub, self
+}
+
+// webSubHeaderLinks picks the hub and self links out of HTTP Link headers,
+// e.g. Link: <https://hub.example.com/>; rel="hub".
+func webSubHeaderLinks(header http.Header) (string, string) {
+	links := []feedLink{}
+	for _, value := range header.Values("Link") {
+		for link := range strings.SplitSeq(value, ",") {
+			target, params, ok := strings.Cut(link, ";")
+			if !ok {
+				continue
+			}
+			target = strings.Trim(strings.TrimSpace(target), "<>")
+			for param := range strings.SplitSeq(params, ";") {
+				name, rel, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "rel") {
+					continue
+				}
+				for r := range strings.FieldsSeq(strings.Trim(rel, `"`)) {
+					links = append(links, feedLink{Href: target, Rel: strings.ToLower(r)})
+				}
+			}
+		}
+	}
+
+	return webSubLinks(links)
+}
@@ -1,10 +1,15 @@
 package worker
 
 import (
+	"cmp"
 	"context"
+	"crypto/rand"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -12,6 +17,7 @@ import (
 	"go.temporal.io/sdk/temporal"
 
 	seyerrs "github.com/jdholdren/seymour/internal/errors"
+	"github.com/jdholdren/seymour/internal/secrets"
 	"github.com/jdholdren/seymour/internal/seymour"
 	"github.com/jdholdren/seymour/internal/sync"
 )
@@ -19,82 +25,584 @@ import (
 type activities struct {
 	repo         seymour.Repository
 	claudeClient *anthropic.Client
+	fetcher      *sync.Fetcher
+	secrets      *secrets.Box // Opens the credentials of private feeds
+
+	// Where hubs push WebSub updates to, e.g. https://seymour.example.com. Empty disables WebSub.
+	webSubCallbackURL string
+
+	// The API server's public url, for links back to it like a watched page's diffs.
+	publicURL string
 }
 
 // Instance to make the workflow a bit more readable
 var acts = activities{}
 
-// Count all feeds we know about in the system.
+// How many syncs in a row can fail before a feed is marked broken.
 //
-// Used for batching up the work as the number of feeds grows.
-func (a activities) CountAllFeeds(ctx context.Context) (int, error) {
-	n, err := a.repo.CountAllFeeds(ctx)
-	if err != nil {
-		return 0, err
-	}
+// With the backoff between them, that's a few days of failures.
+const maxConsecutiveFailures = 10
+
+const (
+	// How long WebSub subscriptions are asked for, and how early they're renewed.
+	webSubLease        = 7 * 24 * time.Hour
+	webSubRenewalLead  = 24 * time.Hour
+	webSubVerifyWithin = time.Hour // Before asking again if the hub never verified
+
+	// Feeds pushed to us are still polled now and then, in case the hub drops the subscription.
+	pushedSyncInterval = 24 * time.Hour
+)
 
-	return n, nil
+// DueFeed is a feed that's due to be synced, along with the host it's fetched from.
+type DueFeed struct {
+	ID   string
+	Host string
 }
 
-// FeedIDPage fetches a page of feed ids from the DB.
+// DueFeedPage fetches a page of the feeds that are due to be synced.
 //
-// Useful for batching work across the global set of feeds.
-func (a activities) FeedIDPage(ctx context.Context, offset, pageSize int) ([]string, error) {
-	ids, err := a.repo.FeedIDs(ctx, offset, pageSize)
+// Pages follow on from the last id of the previous page, starting from an empty one.
+func (a activities) DueFeedPage(ctx context.Context, after string, pageSize int) ([]DueFeed, error) {
+	ids, err := a.repo.DueFeedIDs(ctx, time.Now(), after, pageSize)
 	if err != nil {
 		return nil, err
 	}
+	if len(ids) == 0 {
+		return []DueFeed{}, nil
+	}
 
-	return ids, nil
+	feeds, err := a.repo.Feeds(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching due feeds: %s", err)
+	}
+	hosts := make(map[string]string, len(feeds))
+	for _, feed := range feeds {
+		// Sources go easy on their APIs together, e.g. all of the github: feeds
+		if feed.Kind != "" && feed.Kind != seymour.FeedKindFeed {
+			hosts[feed.ID] = string(feed.Kind) + ":"
+			continue
+		}
+
+		// Anything unparseable lands under the empty host, the sync will report the problem
+		if u, err := url.Parse(feed.URL); err == nil {
+			hosts[feed.ID] = strings.ToLower(u.Hostname())
+		}
+	}
+
+	// Kept in id order, since the next page carries on from the last one
+	due := make([]DueFeed, 0, len(ids))
+	for _, id := range ids {
+		due = append(due, DueFeed{ID: id, Host: hosts[id]})
+	}
+
+	return due, nil
+}
+
+// DeferFeeds pushes back the next sync of the feeds, e.g. when their host asked for a break.
+func (a activities) DeferFeeds(ctx context.Context, feedIDs []string, until time.Time) error {
+	for _, id := range feedIDs {
+		if err := a.repo.UpdateFeed(ctx, id, seymour.UpdateFeedArgs{
+			NextSync: seymour.DBTime{Time: until.UTC()},
+		}); err != nil {
+			return fmt.Errorf("error deferring feed: %s", err)
+		}
+	}
+
+	return nil
 }
 
 // Goes to the url and grabs the RSS feed items.
-func (a activities) SyncFeed(ctx context.Context, feedID string, ignoreRecency bool) error {
+func (a activities) SyncFeed(ctx context.Context, feedID string, ignoreSchedule bool) error {
 	feed, err := a.repo.Feed(ctx, feedID)
 	if err != nil {
 		return err
 	}
 
-	// If recently synced, exit early, don't repeat work:
-	if !ignoreRecency && feed.LastSyncedAt != nil && time.Since(feed.LastSyncedAt.Time) < time.Hour {
+	// If it isn't due yet or is broken, exit early, don't repeat work. A retry is the same
+	// sync over again, backed off by the attempt before it, so it goes ahead regardless.
+	retry := activity.GetInfo(ctx).Attempt > 1
+	if !ignoreSchedule && !retry && (feed.Status == seymour.FeedStatusBroken || (feed.NextSyncAt != nil && time.Now().Before(feed.NextSyncAt.Time))) {
 		return nil
 	}
 
-	feed, entries, err := sync.Feed(ctx, feed.ID, feed.URL)
+	return a.syncFeed(ctx, feed, retry, nil)
+}
+
+// SyncFeedSettings syncs a known feed with the new credentials or source settings it's being
+// subscribed with, whether or not it's due, and only stores them once that works.
+//
+// Until then the feed keeps the settings it had, and settings that don't work aren't counted
+// as one of its failures.
+func (a activities) SyncFeedSettings(ctx context.Context, feedID, credentials, sourceConfig string) error {
+	feed, err := a.repo.Feed(ctx, feedID)
+	if err != nil {
+		return err
+	}
+
+	settings := fetchSettings(credentials, sourceConfig)
+	if settings.Credentials != nil {
+		feed.Credentials = settings.Credentials
+	}
+	if settings.SourceConfig != nil {
+		feed.SourceConfig = settings.SourceConfig
+	}
+	// The new settings are a fix for a broken feed as often as not
+	settings.Status = seymour.FeedStatusActive
+
+	return a.syncFeed(ctx, feed, false, &settings)
+}
+
+// syncFeed fetches the feed and stores what's new. The settings it's being tried with, if any,
+// are stored along with a successful sync, while a failed one isn't recorded on the feed.
+func (a activities) syncFeed(ctx context.Context, feed seymour.Feed, retry bool, settings *seymour.UpdateFeedArgs) error {
+	// Newsletters are delivered to the inbox, there's nothing to fetch
+	if feed.Kind == seymour.FeedKindNewsletter {
+		return nil
+	}
+
+	var (
+		noFailures = 0
+		now        = time.Now()
+	)
+	fetcher, err := a.fetcherFor(feed.Credentials)
+	if err != nil {
+		return err
+	}
+	synced, entries, err := fetcher.Feed(ctx, feed)
+	if err == nil || errors.Is(err, sync.ErrNotModified) {
+		// The feed moved for good: follow it so the old url doesn't cost a redirect every time
+		if synced.URL != "" && synced.URL != feed.URL {
+			movedID, err := a.repo.MoveFeed(ctx, feed.ID, synced.URL)
+			if err != nil {
+				return fmt.Errorf("error moving feed: %s", err)
+			}
+
+			activity.GetLogger(ctx).Info("feed moved", "feed_id", feed.ID, "url", synced.URL, "now_feed_id", movedID)
+			feed.ID = movedID
+			for i := range entries {
+				entries[i].FeedID = movedID
+			}
+		}
+	}
+	if errors.Is(err, sync.ErrNotModified) {
+		// Nothing new to parse, but it still counts as a sync
+		next := sync.Reschedule(feed, 0, now)
+		if synced.NextSyncAt != nil {
+			next = *synced.NextSyncAt
+		}
+		args := seymour.UpdateFeedArgs{
+			LastSynced:          seymour.DBTime{Time: now},
+			NextSync:            next,
+			LastSuccess:         seymour.DBTime{Time: now},
+			ConsecutiveFailures: &noFailures,
+		}
+		withSettings(&args, settings)
+		return a.repo.UpdateFeed(ctx, feed.ID, args)
+	}
 	if err != nil {
-		return temporal.NewApplicationError("error syncing feed", "seyerr", seyerrs.E(err, http.StatusBadRequest))
+		if settings == nil {
+			if err := a.recordFailure(ctx, feed, err, retry); err != nil {
+				return err
+			}
+		}
+
+		// Not retried here: a host asking for a break is flagged so the rest of its feeds can wait too
+		if retryErr := (&sync.RetryAfterError{}); errors.As(err, &retryErr) {
+			return temporal.NewApplicationErrorWithOptions("feed host rate limited", errTypeRateLimit, temporal.ApplicationErrorOptions{
+				NonRetryable: true,
+				Details:      []any{seyerrs.E(err, http.StatusBadRequest), retryErr.Until},
+			})
+		}
+		// A blip, like a timeout or a server error, is left to the activity's retries. Anything
+		// else would only fail the same way, the backoff on the feed takes care of trying again later.
+		if sync.Temporary(err) {
+			return temporal.NewApplicationErrorWithOptions("error syncing feed", "seyerr", temporal.ApplicationErrorOptions{
+				Details: []any{seyerrs.E(err, http.StatusBadGateway)},
+			})
+		}
+		return temporal.NewApplicationErrorWithOptions("error syncing feed", "seyerr", temporal.ApplicationErrorOptions{
+			NonRetryable: true,
+			Details:      []any{seyerrs.E(err, http.StatusBadRequest)},
+		})
+	}
+
+	// A watched page's snapshot only becomes an entry when the page changed
+	if feed.Kind == seymour.FeedKindWatch {
+		if entries, err = a.pageChanges(ctx, feed, entries); err != nil {
+			return err
+		}
+	}
+
+	var warning string
+	if synced.ParseWarning != nil {
+		warning = *synced.ParseWarning
 	}
+	args := seymour.UpdateFeedArgs{
+		Title:        *synced.Title,
+		Description:  *synced.Description,
+		LastSynced:   seymour.DBTime{Time: now},
+		ParseWarning: &warning,
+		SyncInterval: synced.SyncInterval,
+		SkipHours:    synced.SkipHours,
+		SkipDays:     synced.SkipDays,
+		HubURL:       cmp.Or(synced.HubURL, new(string)),
+		TopicURL:     cmp.Or(synced.TopicURL, new(string)),
 
-	if err := a.repo.UpdateFeed(ctx, feed.ID, seymour.UpdateFeedArgs{
-		Title:       *feed.Title,
-		Description: *feed.Description,
-		LastSynced:  seymour.DBTime{Time: time.Now()},
-	}); err != nil {
+		LastSuccess:         seymour.DBTime{Time: now},
+		ConsecutiveFailures: &noFailures,
+	}
+	if synced.NextSyncAt != nil {
+		args.NextSync = *synced.NextSyncAt
+	}
+	if a.pushed(ctx, feed.ID, now) {
+		args.NextSync = seymour.DBTime{Time: now.Add(pushedSyncInterval)}
+	}
+	if synced.ETag != nil {
+		args.ETag = *synced.ETag
+	}
+	if synced.LastModified != nil {
+		args.LastModified = *synced.LastModified
+	}
+	withSettings(&args, settings)
+	if err := a.repo.UpdateFeed(ctx, feed.ID, args); err != nil {
 		return err
 	}
 	if err := a.repo.InsertEntries(ctx, entries); err != nil {
 		return err
 	}
+	// Only moved along once the entries it's past are in
+	if synced.SourceCursor != nil {
+		if err := a.repo.UpdateFeed(ctx, feed.ID, seymour.UpdateFeedArgs{SourceCursor: synced.SourceCursor}); err != nil {
+			return err
+		}
+	}
 
 	return err
 }
 
-func (a activities) CreateFeed(ctx context.Context, feedURL string) (string, error) {
-	feed, err := a.repo.InsertFeed(ctx, feedURL)
+// pageChanges compares a watched page's snapshot to its last one, storing it if it changed and
+// giving an entry that sums up the change, with the diff as its content.
+//
+// The first snapshot is only kept to compare against, it gives no entry.
+func (a activities) pageChanges(ctx context.Context, feed seymour.Feed, entries []seymour.FeedEntry) ([]seymour.FeedEntry, error) {
+	if len(entries) != 1 {
+		return nil, fmt.Errorf("expected a single snapshot of the page, got %d", len(entries))
+	}
+	current := entries[0]
+
+	latest, err := a.repo.LatestSnapshot(ctx, feed.ID)
+	if err != nil && !errors.Is(err, seymour.ErrNotFound) {
+		return nil, fmt.Errorf("error fetching latest snapshot: %s", err)
+	}
+	first := errors.Is(err, seymour.ErrNotFound)
+	if !first && latest.ContentHash == current.GUID {
+		return []seymour.FeedEntry{}, nil
+	}
+
+	snapshot, err := a.repo.InsertSnapshot(ctx, seymour.PageSnapshot{
+		FeedID:      feed.ID,
+		Title:       current.Title,
+		Content:     current.Content,
+		ContentHash: current.GUID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if first {
+		return []seymour.FeedEntry{}, nil
+	}
+
+	link := current.Link
+	if a.publicURL != "" {
+		link = fmt.Sprintf("%s/api/snapshots/%s/diff", strings.TrimSuffix(a.publicURL, "/"), snapshot.ID)
+	}
+	diff := sync.Diff(latest.Content, snapshot.Content)
+
+	// A page can change back to how it was, so it's the snapshot that tells changes apart, not the text
+	return []seymour.FeedEntry{{
+		FeedID:      current.FeedID,
+		GUID:        snapshot.ID,
+		Title:       fmt.Sprintf("%s changed", cmp.Or(current.Title, current.Link)),
+		Description: sync.SummarizeDiff(diff),
+		Content:     sync.DiffHTML(diff),
+		Link:        link,
+		PublishTime: current.PublishTime,
+	}}, nil
+}
+
+// pushed checks if the feed's hub is currently pushing updates to us.
+func (a activities) pushed(ctx context.Context, feedID string, now time.Time) bool {
+	sub, err := a.repo.WebSubSubscription(ctx, feedID)
+	if err != nil {
+		return false
+	}
+
+	return sub.State == seymour.WebSubStateVerified && sub.LeaseExpiresAt != nil && now.Before(sub.LeaseExpiresAt.Time)
+}
+
+// FeedsNeedingWebSub finds the feeds whose hubs need a (new) subscription request.
+func (a activities) FeedsNeedingWebSub(ctx context.Context) ([]string, error) {
+	if a.webSubCallbackURL == "" {
+		return []string{}, nil
+	}
+
+	now := time.Now()
+	ids, err := a.repo.FeedsNeedingWebSub(ctx, now.Add(webSubRenewalLead), now.Add(-webSubVerifyWithin))
+	if err != nil {
+		return nil, fmt.Errorf("error finding feeds needing websub: %s", err)
+	}
+
+	return ids, nil
+}
+
+// SubscribeWebSub asks the feed's hub to push updates to our callback.
+//
+// The subscription stays pending until the hub calls back to verify it.
+func (a activities) SubscribeWebSub(ctx context.Context, feedID string) error {
+	feed, err := a.repo.Feed(ctx, feedID)
+	if err != nil {
+		return err
+	}
+	if feed.HubURL == nil || feed.TopicURL == nil {
+		return nil
+	}
+
+	sub := seymour.WebSubSubscription{
+		FeedID:   feed.ID,
+		HubURL:   *feed.HubURL,
+		TopicURL: *feed.TopicURL,
+		Secret:   rand.Text(),
+		State:    seymour.WebSubStatePending,
+	}
+	// Saved first, since the hub can call back to verify before it even responds
+	if err := a.repo.UpsertWebSubSubscription(ctx, sub); err != nil {
+		return err
+	}
+
+	callback := fmt.Sprintf("%s/api/websub/%s", strings.TrimSuffix(a.webSubCallbackURL, "/"), feed.ID)
+	if err := a.fetcher.Subscribe(ctx, sub.HubURL, sub.TopicURL, callback, sub.Secret, webSubLease); err != nil {
+		return fmt.Errorf("error subscribing to hub: %s", err)
+	}
+
+	return nil
+}
+
+// withSettings adds the settings a feed synced with to its update, if it was tried with new ones.
+func withSettings(args *seymour.UpdateFeedArgs, settings *seymour.UpdateFeedArgs) {
+	if settings == nil {
+		return
+	}
+
+	args.Credentials = settings.Credentials
+	args.SourceConfig = settings.SourceConfig
+	args.Status = settings.Status
+}
+
+// recordFailure notes a failed sync on the feed and backs it off, marking it broken once
+// it's failed too many times in a row.
+func (a activities) recordFailure(ctx context.Context, feed seymour.Feed, syncErr error, retry bool) error {
+	now := time.Now()
+	args := seymour.UpdateFeedArgs{
+		LastError:   syncErr.Error(),
+		LastErrorAt: seymour.DBTime{Time: now},
+	}
+
+	// The server's fine, it just wants to be left alone for as long as it asked
+	if retryErr := (&sync.RetryAfterError{}); errors.As(syncErr, &retryErr) {
+		args.NextSync = seymour.DBTime{Time: retryErr.Until}
+		return a.repo.UpdateFeed(ctx, feed.ID, args)
+	}
+
+	// A retry only updates the error, the failure was counted on the first attempt
+	if retry {
+		return a.repo.UpdateFeed(ctx, feed.ID, args)
+	}
+
+	failures := feed.ConsecutiveFailures + 1
+	args.ConsecutiveFailures = &failures
+	args.NextSync = sync.Backoff(failures, now)
+	if failures >= maxConsecutiveFailures {
+		args.Status = seymour.FeedStatusBroken
+	}
+
+	return a.repo.UpdateFeed(ctx, feed.ID, args)
+}
+
+// fetcherFor gives the fetcher to use with a feed's sealed credentials, if it has any.
+func (a activities) fetcherFor(credentials *string) (*sync.Fetcher, error) {
+	if credentials == nil || *credentials == "" {
+		return a.fetcher, nil
+	}
+
+	opened, err := a.secrets.Open(*credentials)
+	if err != nil {
+		return nil, fmt.Errorf("error opening feed credentials: %s", err)
+	}
+	var creds seymour.FeedCredentials
+	if err := json.Unmarshal(opened, &creds); err != nil {
+		return nil, fmt.Errorf("error decoding feed credentials: %s", err)
+	}
+
+	return a.fetcher.WithAuth(creds), nil
+}
+
+// DiscoverFeeds resolves the URL someone wants to subscribe to into the feeds it offers.
+//
+// Feed URLs resolve to themselves, while website URLs are searched for the feeds they advertise.
+// Private feeds are fetched with their sealed credentials, empty for public ones.
+func (a activities) DiscoverFeeds(ctx context.Context, feedURL, credentials string) ([]string, error) {
+	fetcher, err := a.fetcherFor(&credentials)
+	if err != nil {
+		return nil, temporal.NewApplicationError("error with credentials", "seyerr", seyerrs.E(err, http.StatusBadRequest))
+	}
+	candidates, err := fetcher.Discover(ctx, feedURL)
+	if err != nil {
+		return nil, temporal.NewApplicationError("error discovering feeds", "seyerr", seyerrs.E(err, http.StatusBadRequest))
+	}
+	if len(candidates) == 0 {
+		return nil, temporal.NewApplicationError("no feeds found", "seyerr", seyerrs.E("no feeds found at url", http.StatusBadRequest))
+	}
+
+	return candidates, nil
+}
+
+// CreatedFeed is the feed [activities.CreateFeed] found or inserted.
+type CreatedFeed struct {
+	ID       string
+	Inserted bool // False if it was already known
+
+	// Set when a known feed is being subscribed with credentials or source settings other than the
+	// ones it has. They're left for [activities.SyncFeedSettings] to store once they work.
+	NewSettings bool
+}
+
+// CreateFeed inserts the feed if it isn't already known, storing any sealed credentials it's fetched
+// with and the settings of its source.
+//
+// A known feed's settings aren't touched, so a subscription with wrong ones can't break it.
+func (a activities) CreateFeed(ctx context.Context, feedURL, credentials, sourceConfig string) (CreatedFeed, error) {
+	// It might already be known, possibly by a url it used to have
+	feed, err := a.repo.FeedByURL(ctx, feedURL)
+	if err == nil {
+		return knownFeed(feed, credentials, sourceConfig), nil
+	}
+	if !errors.Is(err, seymour.ErrNotFound) {
+		return CreatedFeed{}, fmt.Errorf("error fetching feed: %s", err)
+	}
+
+	feed, err = a.repo.InsertFeed(ctx, feedURL, a.fetcher.Kind(feedURL))
 	if errors.Is(err, seymour.ErrConflict) {
 		// Fetch the feed from the database
 		feed, err = a.repo.FeedByURL(ctx, feedURL)
 		if err != nil {
-			return "", fmt.Errorf("error fetching conflicting feed: %s", err)
+			return CreatedFeed{}, fmt.Errorf("error fetching conflicting feed: %s", err)
 		}
 
-		return feed.ID, nil
+		return knownFeed(feed, credentials, sourceConfig), nil
 	}
 	if err != nil {
-		return "", fmt.Errorf("error inserting feed: %w", err)
+		return CreatedFeed{}, fmt.Errorf("error inserting feed: %w", err)
+	}
+
+	if args := fetchSettings(credentials, sourceConfig); args.Credentials != nil || args.SourceConfig != nil {
+		if err := a.repo.UpdateFeed(ctx, feed.ID, args); err != nil {
+			return CreatedFeed{}, fmt.Errorf("error storing feed settings: %s", err)
+		}
+	}
+
+	return CreatedFeed{ID: feed.ID, Inserted: true}, nil
+}
+
+// knownFeed checks whether a feed that already exists is being subscribed with new settings.
+//
+// Sealed credentials are never the same twice, so any given are taken as new.
+func knownFeed(feed seymour.Feed, credentials, sourceConfig string) CreatedFeed {
+	newConfig := sourceConfig != "" && (feed.SourceConfig == nil || *feed.SourceConfig != sourceConfig)
+	return CreatedFeed{ID: feed.ID, NewSettings: credentials != "" || newConfig}
+}
+
+// fetchSettings is the update storing a feed's new sealed credentials and source settings, leaving
+// the current ones for whichever there aren't new ones of.
+func fetchSettings(credentials, sourceConfig string) seymour.UpdateFeedArgs {
+	var args seymour.UpdateFeedArgs
+	if credentials != "" {
+		args.Credentials = &credentials
+	}
+	if sourceConfig != "" {
+		args.SourceConfig = &sourceConfig
+	}
+
+	return args
+}
+
+// ScrapePreview is what a scraped feed would look like, see [activities.PreviewScrape].
+type ScrapePreview struct {
+	Title   string
+	Entries []seymour.FeedEntry
+}
+
+// PreviewScrape picks the entries out of the page with the config's selectors, without saving them.
+func (a activities) PreviewScrape(ctx context.Context, pageURL string, cfg seymour.ScrapeConfig) (ScrapePreview, error) {
+	feed, entries, err := a.fetcher.Scrape(ctx, pageURL, cfg)
+	if err != nil {
+		return ScrapePreview{}, temporal.NewApplicationErrorWithOptions("error scraping page", "seyerr", temporal.ApplicationErrorOptions{
+			NonRetryable: true,
+			Details:      []any{seyerrs.E(err, http.StatusBadRequest)},
+		})
+	}
+
+	return ScrapePreview{Title: *feed.Title, Entries: entries}, nil
+}
+
+// BackfillPage inserts the entries from a page of the feed's history, starting with the feed's own
+// url when pageURL is empty. Returns the page before it, empty when there's nothing older.
+//
+// Entries published before since are left out, and once a page has nothing newer than that,
+// there's no point in walking further back.
+func (a activities) BackfillPage(ctx context.Context, feedID, pageURL string, since time.Time) (string, error) {
+	feed, err := a.repo.Feed(ctx, feedID)
+	if errors.Is(err, seymour.ErrNotFound) {
+		return "", temporal.NewApplicationErrorWithOptions("feed not found", "seyerr", temporal.ApplicationErrorOptions{
+			NonRetryable: true,
+			Details:      []any{seyerrs.E("feed not found", http.StatusNotFound)},
+		})
+	}
+	if err != nil {
+		return "", err
+	}
+	// Sources only have their latest entries, which were already synced
+	if feed.Kind != "" && feed.Kind != seymour.FeedKindFeed {
+		return "", nil
+	}
+
+	fetcher, err := a.fetcherFor(feed.Credentials)
+	if err != nil {
+		return "", err
+	}
+	page, err := fetcher.Page(ctx, feed.ID, cmp.Or(pageURL, feed.URL))
+	if err != nil {
+		return "", fmt.Errorf("error fetching feed page: %s", err)
+	}
+
+	entries := make([]seymour.FeedEntry, 0, len(page.Entries))
+	for _, entry := range page.Entries {
+		// Undated entries can't be placed, so they're kept
+		if !since.IsZero() && !entry.PublishTime.Time.IsZero() && entry.PublishTime.Time.Before(since) {
+			continue
+		}
+		entry.Backfilled = true
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 && len(page.Entries) > 0 {
+		page.Next = ""
+	}
+
+	// Entries that were already synced stay as they were
+	if err := a.repo.InsertEntries(ctx, entries); err != nil {
+		return "", err
 	}
 
-	return feed.ID, nil
+	return page.Next, nil
 }
 
 func (a activities) RemoveFeed(ctx context.Context, feedID string) error {
@@ -1 +1,206 @@
 package worker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	_ "modernc.org/sqlite"
+
+	seyerrs "github.com/jdholdren/seymour/internal/errors"
+	"github.com/jdholdren/seymour/internal/migrations"
+	"github.com/jdholdren/seymour/internal/seymour"
+	"github.com/jdholdren/seymour/internal/sqlite"
+)
+
+// dueFeeds answers DueFeedPage with the feeds in a single page. Their ids are host/n.
+func dueFeeds(env *testsuite.TestWorkflowEnvironment, ids ...string) {
+	page := make([]DueFeed, 0, len(ids))
+	for _, id := range ids {
+		host, _, _ := strings.Cut(id, "/")
+		page = append(page, DueFeed{ID: id, Host: host})
+	}
+
+	env.OnActivity(acts.DueFeedPage, mock.Anything, "", mock.Anything).Return(page, nil)
+	env.OnActivity(acts.DueFeedPage, mock.Anything, mock.Anything, mock.Anything).Return([]DueFeed{}, nil)
+}
+
+func TestSyncAllFeeds_MaxPerHost(t *testing.T) {
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&activities{})
+	dueFeeds(env, "a.example/1", "a.example/2", "a.example/3", "a.example/4", "a.example/5", "b.example/1", "b.example/2")
+
+	var (
+		mu      gosync.Mutex
+		synced  []string
+		running = map[string]int{}
+		most    = map[string]int{}
+	)
+	env.OnActivity(acts.SyncFeed, mock.Anything, mock.Anything, true).Return(func(_ context.Context, id string, _ bool) error {
+		host, _, _ := strings.Cut(id, "/")
+
+		mu.Lock()
+		running[host]++
+		most[host] = max(most[host], running[host])
+		mu.Unlock()
+
+		// Long enough for the others that could be running to have started
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running[host]--
+		synced = append(synced, id)
+		mu.Unlock()
+		return nil
+	})
+
+	env.ExecuteWorkflow(workflows{}.SyncAllFeeds, SyncOptions{MaxConcurrent: 10, MaxPerHost: 2})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	assert.Len(t, synced, 7)
+	assert.LessOrEqual(t, most["a.example"], 2)
+	assert.LessOrEqual(t, most["b.example"], 2)
+}
+
+func TestSyncAllFeeds_RetryAfterDefersHost(t *testing.T) {
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&activities{})
+	dueFeeds(env, "a.example/1", "a.example/2", "a.example/3", "a.example/4", "b.example/1")
+
+	until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	var (
+		mu     gosync.Mutex
+		synced []string
+	)
+	env.OnActivity(acts.SyncFeed, mock.Anything, mock.Anything, true).Return(func(_ context.Context, id string, _ bool) error {
+		mu.Lock()
+		synced = append(synced, id)
+		mu.Unlock()
+
+		if id == "a.example/2" {
+			return temporal.NewApplicationErrorWithOptions("feed host rate limited", errTypeRateLimit, temporal.ApplicationErrorOptions{
+				NonRetryable: true,
+				Details:      []any{seyerrs.E("unexpected status code: 429", http.StatusBadRequest), until},
+			})
+		}
+		return nil
+	})
+
+	var deferred []string
+	env.OnActivity(acts.DeferFeeds, mock.Anything, mock.Anything, mock.Anything).Return(func(_ context.Context, ids []string, deferUntil time.Time) error {
+		assert.True(t, until.Equal(deferUntil))
+		deferred = ids
+		return nil
+	})
+
+	// One at a time, so it's clear which of the host's feeds were left
+	env.ExecuteWorkflow(workflows{}.SyncAllFeeds, SyncOptions{MaxConcurrent: 10, MaxPerHost: 1})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	assert.ElementsMatch(t, []string{"a.example/1", "a.example/2", "b.example/1"}, synced)
+	assert.Equal(t, []string{"a.example/3", "a.example/4"}, deferred)
+	env.AssertExpectations(t)
+}
+
+func TestCreateFeed_RemovesOnlyNewFeeds(t *testing.T) {
+	syncErr := temporal.NewNonRetryableApplicationError("error syncing feed", "seyerr", nil, seyerrs.E("unexpected status code: 401", http.StatusBadRequest))
+
+	t.Run("new feed", func(t *testing.T) {
+		var s testsuite.WorkflowTestSuite
+		env := s.NewTestWorkflowEnvironment()
+		env.RegisterActivity(&activities{})
+		env.OnActivity(acts.DiscoverFeeds, mock.Anything, "https://example.com/feed", "").Return([]string{"https://example.com/feed"}, nil)
+		env.OnActivity(acts.CreateFeed, mock.Anything, "https://example.com/feed", "", "").Return(CreatedFeed{ID: "feed-1", Inserted: true}, nil)
+		env.OnActivity(acts.SyncFeed, mock.Anything, "feed-1", false).Return(syncErr)
+		env.OnActivity(acts.RemoveFeed, mock.Anything, "feed-1").Return(nil).Once()
+
+		env.ExecuteWorkflow(workflows{}.CreateFeed, "https://example.com/feed", "", "")
+		require.True(t, env.IsWorkflowCompleted())
+		require.Error(t, env.GetWorkflowError())
+		env.AssertExpectations(t)
+	})
+
+	t.Run("known feed with new settings", func(t *testing.T) {
+		var s testsuite.WorkflowTestSuite
+		env := s.NewTestWorkflowEnvironment()
+		env.RegisterActivity(&activities{})
+		env.OnActivity(acts.DiscoverFeeds, mock.Anything, "https://example.com/feed", "sealed").Return([]string{"https://example.com/feed"}, nil)
+		env.OnActivity(acts.CreateFeed, mock.Anything, "https://example.com/feed", "sealed", "").Return(CreatedFeed{ID: "feed-1", NewSettings: true}, nil)
+		// Tried with the new credentials right away, rather than whenever the feed's next due
+		env.OnActivity(acts.SyncFeedSettings, mock.Anything, "feed-1", "sealed", "").Return(syncErr).Once()
+		env.OnActivity(acts.RemoveFeed, mock.Anything, mock.Anything).Return(nil)
+
+		env.ExecuteWorkflow(workflows{}.CreateFeed, "https://example.com/feed", "sealed", "")
+		require.True(t, env.IsWorkflowCompleted())
+		require.Error(t, env.GetWorkflowError())
+		env.AssertNotCalled(t, "RemoveFeed", mock.Anything, mock.Anything)
+		env.AssertNotCalled(t, "SyncFeed", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestKnownFeed(t *testing.T) {
+	config := `{"selector":"article"}`
+	feed := seymour.Feed{ID: "feed-1", SourceConfig: &config}
+
+	assert.Equal(t, CreatedFeed{ID: "feed-1"}, knownFeed(feed, "", ""))
+	assert.Equal(t, CreatedFeed{ID: "feed-1"}, knownFeed(feed, "", config))
+	assert.Equal(t, CreatedFeed{ID: "feed-1", NewSettings: true}, knownFeed(feed, "", `{"selector":"li"}`))
+	assert.Equal(t, CreatedFeed{ID: "feed-1", NewSettings: true}, knownFeed(feed, "sealed", ""))
+}
+
+func TestRecordFailure(t *testing.T) {
+	ctx := context.Background()
+	dbx, err := sqlx.Open("sqlite", "file:"+t.TempDir()+"/seymour.sqlite")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = dbx.Close() })
+	require.NoError(t, migrations.Run(dbx))
+
+	var (
+		repo = sqlite.New(dbx)
+		a    = activities{repo: repo}
+	)
+	feed, err := repo.InsertFeed(ctx, "https://example.com/feed.xml", seymour.FeedKindFeed)
+	require.NoError(t, err)
+	reload := func() seymour.Feed {
+		t.Helper()
+
+		feed, err := repo.Feed(ctx, feed.ID)
+		require.NoError(t, err)
+		return feed
+	}
+
+	// The first attempt counts it, the activity's retries of the same sync don't
+	require.NoError(t, a.recordFailure(ctx, reload(), errors.New("unexpected status code: 502"), false))
+	require.NoError(t, a.recordFailure(ctx, reload(), errors.New("unexpected status code: 504"), true))
+	feed = reload()
+	assert.Equal(t, 1, feed.ConsecutiveFailures)
+	require.NotNil(t, feed.LastError)
+	assert.Equal(t, "unexpected status code: 504", *feed.LastError)
+	assert.Equal(t, seymour.FeedStatusActive, feed.Status)
+	require.NotNil(t, feed.NextSyncAt)
+	assert.True(t, feed.NextSyncAt.Time.After(time.Now()))
+
+	// Broken once it's failed too many times in a row
+	failures := maxConsecutiveFailures - 2
+	require.NoError(t, repo.UpdateFeed(ctx, feed.ID, seymour.UpdateFeedArgs{ConsecutiveFailures: &failures}))
+	require.NoError(t, a.recordFailure(ctx, reload(), errors.New("unexpected status code: 404"), false))
+	assert.Equal(t, seymour.FeedStatusActive, reload().Status)
+	require.NoError(t, a.recordFailure(ctx, reload(), errors.New("unexpected status code: 404"), false))
+	feed = reload()
+	assert.Equal(t, maxConsecutiveFailures, feed.ConsecutiveFailures)
+	assert.Equal(t, seymour.FeedStatusBroken, feed.Status)
+}
@@ -2,6 +2,7 @@ package worker
 
 import (
 	"errors"
+	"time"
 
 	"go.temporal.io/sdk/temporal"
 
@@ -23,3 +24,23 @@ func asSeyerr(err error, seyerr **seyerrs.Error) bool {
 	}
 	return appErr.Details(seyerr) == nil
 }
+
+// Unwraps a host asking to be left alone from an activity's error.
+//
+// Returns when the host can be tried again, and whether it asked at all.
+func rateLimitedUntil(err error) (time.Time, bool) {
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || appErr.Type() != errTypeRateLimit {
+		return time.Time{}, false
+	}
+
+	var (
+		seyerr seyerrs.Error
+		until  time.Time
+	)
+	if err := appErr.Details(&seyerr, &until); err != nil || until.IsZero() {
+		return time.Time{}, false
+	}
+
+	return until, true
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"go.temporal.io/sdk/activity"
@@ -20,6 +21,19 @@ var systemPrompt string
 //go:embed user_criteria.txt
 var userCriteria string
 
+// The shape of a feed entry as it's sent to Claude.
+//
+// The full content is left out to keep the prompt small: the teaser is enough to judge by.
+type judgedEntry struct {
+	FeedEntryID string    `json:"feed_entry_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Link        string    `json:"link"`
+	PublishTime time.Time `json:"publish_time"`
+	Authors     []string  `json:"authors,omitempty"`
+	Categories  []string  `json:"categories,omitempty"`
+}
+
 type claudeJudgement struct {
 	FeedEntryID string `json:"feed_entry_id"`
 	Approved    bool   `json:"approved"`
@@ -91,7 +105,19 @@ func (a activities) JudgeEntries(ctx context.Context) (judgements, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error fetching feed entries: %w", err)
 	}
-	byts, _ := json.Marshal(feedEntries)
+	posts := make([]judgedEntry, 0, len(feedEntries))
+	for _, entry := range feedEntries {
+		posts = append(posts, judgedEntry{
+			FeedEntryID: entry.ID,
+			Title:       entry.Title,
+			Description: entry.Description,
+			Link:        entry.Link,
+			PublishTime: entry.PublishTime.Time,
+			Authors:     entry.Authors,
+			Categories:  entry.Categories,
+		})
+	}
+	byts, _ := json.Marshal(posts)
 	userMessage := fmt.Sprintf(userCriteria, prompt.Content, string(byts))
 
 	// Call Claude to judge the entries
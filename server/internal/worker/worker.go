@@ -9,52 +9,89 @@ import (
 	"go.temporal.io/sdk/worker"
 
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/jdholdren/seymour/internal/secrets"
 	"github.com/jdholdren/seymour/internal/seymour"
+	"github.com/jdholdren/seymour/internal/sync"
 )
 
 const TaskQueue = "shared"
 
+// Config holds the worker's settings.
+type Config struct {
+	// The public base url of the API server for WebSub hubs to push to,
+	// leaving it empty means feeds are only ever polled.
+	WebSubCallbackURL string
+
+	// The public base url of the API server, for links back to it from entries, e.g. to a watched
+	// page's diffs. Without it they link to the page itself.
+	PublicURL string
+
+	// How feeds are spread out when they're synced.
+	Sync SyncOptions
+
+	// How feeds are fetched.
+	Fetch sync.FetcherOptions
+
+	// Opens the credentials of private feeds, nil if there's no key for them.
+	Secrets *secrets.Box
+}
+
 // NewWorker sets up the worker with registration of workflows, activities, and schedules.
-func NewWorker(ctx context.Context, repo seymour.Repository, cli client.Client, claudeClient *anthropic.Client) (worker.Worker, error) {
+func NewWorker(ctx context.Context, repo seymour.Repository, cli client.Client, claudeClient *anthropic.Client, cfg Config) (worker.Worker, error) {
+	fetcher, err := sync.NewFetcher(cfg.Fetch)
+	if err != nil {
+		return nil, fmt.Errorf("error creating fetcher: %s", err)
+	}
+
 	a := activities{
-		repo:         repo,
-		claudeClient: claudeClient,
+		repo:              repo,
+		claudeClient:      claudeClient,
+		fetcher:           fetcher,
+		secrets:           cfg.Secrets,
+		webSubCallbackURL: cfg.WebSubCallbackURL,
+		publicURL:         cfg.PublicURL,
 	}
 
 	w := worker.New(cli, TaskQueue, worker.Options{})
 
-	if err := registerEverything(ctx, w, a, cli); err != nil {
+	if err := registerEverything(ctx, w, a, cli, cfg); err != nil {
 		return nil, fmt.Errorf("error registering workflows and activities: %T, %v", err, err)
 	}
 
 	return w, nil
 }
 
-func registerEverything(ctx context.Context, w worker.Worker, a activities, cli client.Client) error {
+func registerEverything(ctx context.Context, w worker.Worker, a activities, cli client.Client, cfg Config) error {
 	// Workflows
 	wfs := workflows{}
 	w.RegisterWorkflow(wfs.SyncAllFeeds)
 	w.RegisterWorkflow(wfs.CreateFeed)
 	w.RegisterWorkflow(wfs.RefreshTimeline)
 	w.RegisterWorkflow(wfs.JudgeTimeline)
+	w.RegisterWorkflow(wfs.RenewWebSubs)
+	w.RegisterWorkflow(wfs.BackfillFeed)
+	w.RegisterWorkflow(wfs.PreviewScrape)
 
 	// Activities
 	w.RegisterActivity(&a)
 
 	// Schedules:
-	// Sync RSS feeds
+	// Sync RSS feeds: each feed has its own schedule, this just checks for the ones that are due
+	syncSpec := client.ScheduleSpec{
+		Intervals: []client.ScheduleIntervalSpec{{Every: 5 * time.Minute}},
+	}
+	syncAction := &client.ScheduleWorkflowAction{
+		ID:        "sync_all",
+		Workflow:  wfs.SyncAllFeeds,
+		Args:      []any{cfg.Sync},
+		TaskQueue: TaskQueue,
+	}
 	handle := cli.ScheduleClient().GetHandle(ctx, "sync_all")
 	if _, err := handle.Describe(ctx); err != nil {
 		handle, err = cli.ScheduleClient().Create(ctx, client.ScheduleOptions{
-			ID: "sync_all",
-			Spec: client.ScheduleSpec{
-				Intervals: []client.ScheduleIntervalSpec{{Every: 15 * time.Minute}},
-			},
-			Action: &client.ScheduleWorkflowAction{
-				ID:        "sync_all",
-				Workflow:  wfs.SyncAllFeeds,
-				TaskQueue: TaskQueue,
-			},
+			ID:                 "sync_all",
+			Spec:               syncSpec,
+			Action:             syncAction,
 			TriggerImmediately: true,
 		})
 		if err != nil {
@@ -63,6 +100,9 @@ func registerEverything(ctx context.Context, w worker.Worker, a activities, cli
 	}
 	if err := handle.Update(ctx, client.ScheduleUpdateOptions{
 		DoUpdate: func(input client.ScheduleUpdateInput) (*client.ScheduleUpdate, error) {
+			// Existing schedules pick up changes to how often and how hard feeds are checked
+			input.Description.Schedule.Spec = &syncSpec
+			input.Description.Schedule.Action = syncAction
 			return &client.ScheduleUpdate{
 				Schedule: &input.Description.Schedule,
 			}, nil
@@ -70,6 +110,23 @@ func registerEverything(ctx context.Context, w worker.Worker, a activities, cli
 	}); err != nil {
 		return err
 	}
+	// Keep WebSub subscriptions alive
+	handle = cli.ScheduleClient().GetHandle(ctx, "renew_websubs")
+	if _, err := handle.Describe(ctx); err != nil {
+		if _, err := cli.ScheduleClient().Create(ctx, client.ScheduleOptions{
+			ID: "renew_websubs",
+			Spec: client.ScheduleSpec{
+				Intervals: []client.ScheduleIntervalSpec{{Every: time.Hour}},
+			},
+			Action: &client.ScheduleWorkflowAction{
+				ID:        "renew_websubs",
+				Workflow:  wfs.RenewWebSubs,
+				TaskQueue: TaskQueue,
+			},
+		}); err != nil {
+			return err
+		}
+	}
 	// Refresh timelines
 	handle = cli.ScheduleClient().GetHandle(ctx, "refresh_timelines")
 	if _, err := handle.Describe(ctx); err != nil {
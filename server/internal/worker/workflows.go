@@ -12,6 +12,7 @@ import (
 	"go.temporal.io/sdk/workflow"
 
 	seyerrs "github.com/jdholdren/seymour/internal/errors"
+	"github.com/jdholdren/seymour/internal/seymour"
 )
 
 // NOTE: The workflow functions are really just methods hanging off of workflows for namespace
@@ -20,9 +21,33 @@ import (
 
 type workflows struct{}
 
-func (w workflows) SyncAllFeeds(ctx workflow.Context) error {
+// How long activities that fetch from other servers get, enough for the fetcher's connect and read timeouts.
+const fetchTimeout = 30 * time.Second
+
+// SyncOptions limits how hard [workflows.SyncAllFeeds] leans on the servers it fetches from.
+//
+// Limits left at zero fall back to the defaults, a zero delay doesn't space requests out at all.
+type SyncOptions struct {
+	MaxConcurrent int           // Feeds synced at once overall
+	MaxPerHost    int           // Feeds synced at once from the same host
+	HostDelay     time.Duration // Between starting requests to the same host
+}
+
+func (o SyncOptions) withDefaults() SyncOptions {
+	if o.MaxConcurrent <= 0 {
+		o.MaxConcurrent = 10
+	}
+	if o.MaxPerHost <= 0 {
+		o.MaxPerHost = 2
+	}
+	o.HostDelay = max(o.HostDelay, 0)
+
+	return o
+}
+
+func (w workflows) SyncAllFeeds(ctx workflow.Context, opts SyncOptions) error {
 	options := workflow.ActivityOptions{
-		StartToCloseTimeout: 3 * time.Second,
+		StartToCloseTimeout: fetchTimeout,
 		RetryPolicy: &temporal.RetryPolicy{
 			InitialInterval:    time.Second,
 			BackoffCoefficient: 2.0,
@@ -31,71 +56,160 @@ func (w workflows) SyncAllFeeds(ctx workflow.Context) error {
 	}
 	ctx = workflow.WithActivityOptions(ctx, options)
 	l := workflow.GetLogger(ctx)
+	opts = opts.withDefaults()
+
+	// Page through the feeds that are due in batches, each feed keeps its own schedule.
+	// They're grouped by host so lots of feeds on the same one don't all hit it at once.
+	var (
+		hosts  []string // In the order they were first seen, keeping the workflow deterministic
+		byHost = map[string][]string{}
+		after  string
+	)
+	for {
+		var page []DueFeed
+		if err := workflow.ExecuteActivity(ctx, acts.DueFeedPage, after, 50).Get(ctx, &page); err != nil {
+			l.Error("failed to get due feeds", "error", err)
+			return err
+		}
+		if len(page) == 0 {
+			break
+		}
+		after = page[len(page)-1].ID
 
-	var allFeedCount int
-	if err := workflow.ExecuteActivity(ctx, acts.CountAllFeeds).Get(ctx, &allFeedCount); err != nil {
-		l.Error("failed to sync all feeds", "error", err)
-		return err
+		for _, feed := range page {
+			if _, ok := byHost[feed.Host]; !ok {
+				hosts = append(hosts, feed.Host)
+			}
+			byHost[feed.Host] = append(byHost[feed.Host], feed.ID)
+		}
 	}
 
-	// Batch each one into a group to sync
-	batches := allFeedCount / 50
-	if batches*50 < allFeedCount {
-		batches += 1
+	var (
+		wg  = workflow.NewWaitGroup(ctx)
+		sem = workflow.NewSemaphore(ctx, int64(opts.MaxConcurrent))
+	)
+	wg.Add(len(hosts))
+	for _, host := range hosts {
+		workflow.Go(ctx, func(ctx workflow.Context) {
+			defer wg.Done()
+
+			syncHost(ctx, sem, host, byHost[host], opts)
+		})
 	}
+	wg.Wait(ctx)
 
-	wg := workflow.NewWaitGroup(ctx)
-	for i := range batches {
-		// Get a page of feed ID's
-		var ids []string
-		if err := workflow.ExecuteActivity(ctx, acts.FeedIDPage, i*50, 50).Get(ctx, &ids); err != nil {
-			l.Error("failed to get feed IDs", "error", err)
-			return err
-		}
-		wg.Add(len(ids))
+	return nil
+}
 
-		for _, id := range ids {
-			workflow.Go(ctx, func(ctx workflow.Context) {
-				defer wg.Done()
+// syncHost works through the due feeds on one host, at most MaxPerHost at a time with
+// HostDelay between each one starting.
+//
+// If the host asks for a break, the feeds it hasn't gotten to yet are put off until then.
+func syncHost(ctx workflow.Context, sem workflow.Semaphore, host string, feedIDs []string, opts SyncOptions) {
+	l := workflow.GetLogger(ctx)
 
-				if err := workflow.ExecuteActivity(ctx, acts.SyncFeed, id, true).Get(ctx, nil); err != nil {
-					l.Error("failed to sync feed", "feed_id", id, "error", err)
+	var (
+		wg         = workflow.NewWaitGroup(ctx)
+		next       int       // The next feed up
+		lastStart  time.Time // When the latest request to the host was (or will be) made
+		deferUntil time.Time // Set once the host has asked for a break
+		deferred   []string
+	)
+	workers := min(opts.MaxPerHost, len(feedIDs))
+	wg.Add(workers)
+	for range workers {
+		workflow.Go(ctx, func(ctx workflow.Context) {
+			defer wg.Done()
+
+			for next < len(feedIDs) && deferUntil.IsZero() {
+				id := feedIDs[next]
+				next++
+
+				// Claim a slot before waiting on it, so the other workers queue up behind it
+				start := workflow.Now(ctx)
+				if !lastStart.IsZero() && lastStart.Add(opts.HostDelay).After(start) {
+					start = lastStart.Add(opts.HostDelay)
+				}
+				lastStart = start
+				if wait := start.Sub(workflow.Now(ctx)); wait > 0 {
+					if err := workflow.Sleep(ctx, wait); err != nil {
+						return
+					}
 				}
-			})
-		}
 
+				if err := sem.Acquire(ctx, 1); err != nil {
+					return
+				}
+				// The host may have asked for a break while this one was waiting
+				if !deferUntil.IsZero() {
+					sem.Release(1)
+					deferred = append(deferred, id)
+					return
+				}
+				err := workflow.ExecuteActivity(ctx, acts.SyncFeed, id, true).Get(ctx, nil)
+				sem.Release(1)
+
+				if until, ok := rateLimitedUntil(err); ok {
+					l.Warn("host rate limited, deferring its feeds", "host", host, "until", until)
+					deferUntil = until
+					return
+				}
+				if err != nil {
+					l.Error("failed to sync feed", "feed_id", id, "error", err)
+				}
+			}
+		})
 	}
 	wg.Wait(ctx)
 
-	return nil
+	deferred = append(deferred, feedIDs[next:]...)
+	if len(deferred) == 0 || deferUntil.IsZero() {
+		return
+	}
+	if err := workflow.ExecuteActivity(ctx, acts.DeferFeeds, deferred, deferUntil).Get(ctx, nil); err != nil {
+		l.Error("failed to defer feeds", "host", host, "error", err)
+	}
 }
 
-func TriggerCreateFeedWorkflow(ctx context.Context, c client.Client, feedURL string) (string, error) {
+// CreateFeedResult is the outcome of the [workflows.CreateFeed] workflow.
+//
+// Exactly one of the fields is set: either the feed was created, or the URL offered
+// several feeds and one of the candidates needs to be picked instead.
+type CreateFeedResult struct {
+	FeedID     string
+	Candidates []string
+}
+
+// TriggerCreateFeedWorkflow runs [workflows.CreateFeed] and waits for it.
+//
+// Credentials for private feeds are passed sealed, so they're never in the clear in the workflow's history.
+// Sources that need settings, like scraped feeds, get them as JSON in sourceConfig.
+func TriggerCreateFeedWorkflow(ctx context.Context, c client.Client, feedURL, credentials, sourceConfig string) (CreateFeedResult, error) {
 	options := client.StartWorkflowOptions{
 		TaskQueue: TaskQueue,
 	}
-	we, err := c.ExecuteWorkflow(ctx, options, workflows{}.CreateFeed, feedURL)
+	we, err := c.ExecuteWorkflow(ctx, options, workflows{}.CreateFeed, feedURL, credentials, sourceConfig)
 	if err != nil {
-		return "", fmt.Errorf("unable to execute workflow: %s", err)
+		return CreateFeedResult{}, fmt.Errorf("unable to execute workflow: %s", err)
 	}
 
-	var feedID string
-	err = we.Get(context.Background(), &feedID)
+	var res CreateFeedResult
+	err = we.Get(context.Background(), &res)
 	seyErr := &seyerrs.Error{}
 	if asSeyerr(err, &seyErr) {
-		return "", seyErr
+		return CreateFeedResult{}, seyErr
 	}
 	if err != nil {
-		return "", fmt.Errorf("error executing workflow: %s", err)
+		return CreateFeedResult{}, fmt.Errorf("error executing workflow: %s", err)
 	}
 
-	return feedID, nil
+	return res, nil
 }
 
-// CreateFeed inserts a new feed, tries to sync, and rolls back if it's unable to.
+// CreateFeed discovers the feed behind the URL, inserts it, tries to sync, and rolls back if it's unable to.
 //
-// Returns the ID of the created feed.
-func (w workflows) CreateFeed(ctx workflow.Context, feedURL string) (string, error) {
+// Returns the ID of the created feed, or the candidates if the URL offers more than one feed.
+func (w workflows) CreateFeed(ctx workflow.Context, feedURL, credentials, sourceConfig string) (CreateFeedResult, error) {
 	options := workflow.ActivityOptions{
 		StartToCloseTimeout: 3 * time.Second,
 		RetryPolicy: &temporal.RetryPolicy{
@@ -107,25 +221,52 @@ func (w workflows) CreateFeed(ctx workflow.Context, feedURL string) (string, err
 	ctx = workflow.WithActivityOptions(ctx, options)
 	l := workflow.GetLogger(ctx)
 
+	// Find the feed: probing common paths takes a few requests, so allow more time
+	var (
+		discoverCtx = workflow.WithStartToCloseTimeout(ctx, 30*time.Second)
+		candidates  []string
+	)
+	if err := workflow.ExecuteActivity(discoverCtx, acts.DiscoverFeeds, feedURL, credentials).Get(ctx, &candidates); err != nil {
+		l.Error("failed to discover feeds", "error", err)
+		return CreateFeedResult{}, err
+	}
+	if len(candidates) > 1 {
+		return CreateFeedResult{Candidates: candidates}, nil
+	}
+	feedURL = candidates[0]
+
 	// Insert the feed
-	var feedID string
-	if err := workflow.ExecuteActivity(ctx, acts.CreateFeed, feedURL).Get(ctx, &feedID); err != nil {
+	var created CreatedFeed
+	if err := workflow.ExecuteActivity(ctx, acts.CreateFeed, feedURL, credentials, sourceConfig).Get(ctx, &created); err != nil {
 		l.Error("failed to create feed", "error", err)
-		return "", err
+		return CreateFeedResult{}, err
 	}
-
-	// Sync the feed
-	err := workflow.ExecuteActivity(ctx, acts.SyncFeed, feedID).Get(ctx, nil)
-	if err != nil {
+	feedID := created.ID
+
+	// Sync the feed, right away with any new settings since they aren't kept unless they work
+	var (
+		syncCtx = workflow.WithStartToCloseTimeout(ctx, fetchTimeout)
+		synced  workflow.Future
+	)
+	if created.NewSettings {
+		synced = workflow.ExecuteActivity(syncCtx, acts.SyncFeedSettings, feedID, credentials, sourceConfig)
+	} else {
+		synced = workflow.ExecuteActivity(syncCtx, acts.SyncFeed, feedID, false)
+	}
+	if err := synced.Get(ctx, nil); err != nil {
 		l.Error("failed to sync feed", "feed_id", feedID, "error", err)
 
-		// If there's an issue syncing, remove the feed
+		// If there's an issue syncing, remove the feed. Only if it's new though, one that was
+		// already known has its own history and subscription.
+		if !created.Inserted {
+			return CreateFeedResult{}, err
+		}
 		if err := workflow.ExecuteActivity(ctx, acts.RemoveFeed, feedID).Get(ctx, nil); err != nil {
 			l.Error("failed to remove feed", "feed_id", feedID, "error", err)
-			return "", err
+			return CreateFeedResult{}, err
 		}
 
-		return "", err
+		return CreateFeedResult{}, err
 	}
 
 	// Trigger a refresh of the timeline
@@ -138,10 +279,182 @@ func (w workflows) CreateFeed(ctx workflow.Context, feedURL string) (string, err
 	})
 	if err := workflow.ExecuteChildWorkflow(ctx, workflows.RefreshTimeline).GetChildWorkflowExecution().Get(ctx, nil); err != nil {
 		l.Error("failed to start child workflow", "error", err)
-		return "", err
+		return CreateFeedResult{}, err
+	}
+
+	return CreateFeedResult{FeedID: feedID}, nil
+}
+
+// BackfillOptions limits how far back [workflows.BackfillFeed] goes.
+type BackfillOptions struct {
+	MaxPages int       // Including the feed itself, zero falls back to the default
+	Since    time.Time // Entries published before this are skipped, zero for no limit
+}
+
+// The most pages a backfill walks through, however many are asked for.
+const maxBackfillPages = 100
+
+func (o BackfillOptions) withDefaults() BackfillOptions {
+	if o.MaxPages <= 0 {
+		o.MaxPages = 10
+	}
+	o.MaxPages = min(o.MaxPages, maxBackfillPages)
+
+	return o
+}
+
+// TriggerBackfillWorkflow starts backfilling the feed without waiting for it.
+//
+// Backfills of a feed that's already being backfilled are folded into the running one.
+func TriggerBackfillWorkflow(ctx context.Context, c client.Client, feedID string, opts BackfillOptions) error {
+	options := client.StartWorkflowOptions{
+		ID:                       "backfill-" + feedID,
+		TaskQueue:                TaskQueue,
+		WorkflowIDConflictPolicy: enums.WORKFLOW_ID_CONFLICT_POLICY_USE_EXISTING,
+	}
+	if _, err := c.ExecuteWorkflow(ctx, options, workflows{}.BackfillFeed, feedID, opts); err != nil {
+		return fmt.Errorf("unable to execute workflow: %s", err)
+	}
+
+	return nil
+}
+
+// BackfillFeed walks back through a feed's older pages or archives, inserting the entries
+// it didn't have yet as backfilled, and then refreshes the timeline with them.
+func (w workflows) BackfillFeed(ctx workflow.Context, feedID string, opts BackfillOptions) error {
+	options := workflow.ActivityOptions{
+		StartToCloseTimeout: fetchTimeout,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumAttempts:    3,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, options)
+	l := workflow.GetLogger(ctx)
+	opts = opts.withDefaults()
+
+	var (
+		pageURL string // The feed itself to start
+		visited = map[string]bool{}
+	)
+	for page := 0; page < opts.MaxPages; page++ {
+		var next string
+		if err := workflow.ExecuteActivity(ctx, acts.BackfillPage, feedID, pageURL, opts.Since).Get(ctx, &next); err != nil {
+			// Whatever was inserted before this page is still worth showing
+			l.Error("failed to backfill page", "feed_id", feedID, "page_url", pageURL, "error", err)
+			break
+		}
+
+		// Archives that link in a circle would otherwise be walked until the page limit
+		visited[pageURL] = true
+		if next == "" || visited[next] {
+			break
+		}
+		pageURL = next
+	}
+
+	ctx = workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+		// Ensure only one judgement at a time, allow current one to process
+		WorkflowID:            "refresh-timeline",
+		WorkflowIDReusePolicy: enums.WORKFLOW_ID_REUSE_POLICY_TERMINATE_IF_RUNNING,
+		ParentClosePolicy:     enums.PARENT_CLOSE_POLICY_ABANDON,
+		TaskQueue:             TaskQueue,
+	})
+	if err := workflow.ExecuteChildWorkflow(ctx, workflows.RefreshTimeline).GetChildWorkflowExecution().Get(ctx, nil); err != nil {
+		l.Error("failed to start child workflow", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// TriggerPreviewScrapeWorkflow runs [workflows.PreviewScrape] and waits for it.
+func TriggerPreviewScrapeWorkflow(ctx context.Context, c client.Client, pageURL string, cfg seymour.ScrapeConfig) (ScrapePreview, error) {
+	options := client.StartWorkflowOptions{
+		TaskQueue: TaskQueue,
+	}
+	we, err := c.ExecuteWorkflow(ctx, options, workflows{}.PreviewScrape, pageURL, cfg)
+	if err != nil {
+		return ScrapePreview{}, fmt.Errorf("unable to execute workflow: %s", err)
+	}
+
+	var preview ScrapePreview
+	err = we.Get(context.Background(), &preview)
+	seyErr := &seyerrs.Error{}
+	if asSeyerr(err, &seyErr) {
+		return ScrapePreview{}, seyErr
+	}
+	if err != nil {
+		return ScrapePreview{}, fmt.Errorf("error executing workflow: %s", err)
 	}
 
-	return feedID, nil
+	return preview, nil
+}
+
+// PreviewScrape tries a scraped feed's selectors out on its page, without saving anything.
+func (w workflows) PreviewScrape(ctx workflow.Context, pageURL string, cfg seymour.ScrapeConfig) (ScrapePreview, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: fetchTimeout,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 1, // Someone's waiting on it
+		},
+	})
+
+	var preview ScrapePreview
+	if err := workflow.ExecuteActivity(ctx, acts.PreviewScrape, pageURL, cfg).Get(ctx, &preview); err != nil {
+		workflow.GetLogger(ctx).Error("failed to preview scrape", "error", err)
+		return ScrapePreview{}, err
+	}
+
+	return preview, nil
+}
+
+// RenewWebSubs asks hubs to push updates for the feeds that advertise one, renewing
+// subscriptions before their leases run out.
+func (w workflows) RenewWebSubs(ctx workflow.Context) error {
+	options := workflow.ActivityOptions{
+		StartToCloseTimeout: fetchTimeout,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumAttempts:    3,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, options)
+	l := workflow.GetLogger(ctx)
+
+	var ids []string
+	if err := workflow.ExecuteActivity(ctx, acts.FeedsNeedingWebSub).Get(ctx, &ids); err != nil {
+		l.Error("failed to find feeds needing websub", "error", err)
+		return err
+	}
+
+	// One hub failing shouldn't hold up the others, it'll be tried again next time
+	for _, id := range ids {
+		if err := workflow.ExecuteActivity(ctx, acts.SubscribeWebSub, id).Get(ctx, nil); err != nil {
+			l.Error("failed to subscribe to hub", "feed_id", id, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// TriggerRefreshTimelineWorkflow starts refreshing the timeline without waiting for it, e.g.
+// after entries were pushed to us.
+//
+// Refreshes triggered while one is already running are folded into it.
+func TriggerRefreshTimelineWorkflow(ctx context.Context, c client.Client) error {
+	options := client.StartWorkflowOptions{
+		ID:                       "refresh-timeline-push",
+		TaskQueue:                TaskQueue,
+		WorkflowIDConflictPolicy: enums.WORKFLOW_ID_CONFLICT_POLICY_USE_EXISTING,
+	}
+	if _, err := c.ExecuteWorkflow(ctx, options, workflows{}.RefreshTimeline); err != nil {
+		return fmt.Errorf("unable to execute workflow: %s", err)
+	}
+
+	return nil
 }
 
 // RefreshTimeline syncs any missing entries based on